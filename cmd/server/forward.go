@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+)
+
+// forwardedHeader marks requests that have already been proxied once, so a
+// leader change mid-flight cannot bounce a request around the cluster forever.
+const forwardedHeader = "X-DistRAFT-Forwarded-By"
+
+// forwardToLeader proxies a write request to the current leader's HTTP API.
+// It returns false when this node is the leader and should handle the request itself.
+func forwardToLeader(w http.ResponseWriter, r *http.Request, rNode *consensus.RaftNode, nodeID string) bool {
+	if rNode.IsLeader() {
+		return false
+	}
+
+	if via := r.Header.Get(forwardedHeader); via != "" {
+		http.Error(w, "Leader changed while forwarding (via "+via+"), retry", http.StatusServiceUnavailable)
+		return true
+	}
+
+	leaderHTTP, err := rNode.LeaderHTTPAddr()
+	if err != nil {
+		http.Error(w, "Cannot forward to leader: "+err.Error(), http.StatusServiceUnavailable)
+		return true
+	}
+
+	target := &url.URL{Scheme: "http", Host: leaderHTTP}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Printf("⚠️ Forwarding %s to leader %s failed: %v", r.URL.Path, leaderHTTP, err)
+		http.Error(w, "Leader unreachable: "+err.Error(), http.StatusBadGateway)
+	}
+
+	r.Header.Set(forwardedHeader, nodeID)
+	proxy.ServeHTTP(w, r)
+	return true
+}
+
+// prepareRead applies the read consistency requested in the "consistency" query parameter:
+//
+//	stale    any node answers from local memory (may lag the leader)
+//	default  the leader answers from local memory (followers forward)
+//	strong   the leader confirms it still leads before answering (linearizable)
+//
+// It returns true when the response has already been written (forwarded or rejected).
+func prepareRead(w http.ResponseWriter, r *http.Request, rNode *consensus.RaftNode, nodeID string) bool {
+	switch r.URL.Query().Get("consistency") {
+	case "stale":
+		return false
+	case "", "default":
+		return forwardToLeader(w, r, rNode, nodeID)
+	case "strong":
+		if forwardToLeader(w, r, rNode, nodeID) {
+			return true
+		}
+		if err := rNode.ConsistentRead(5 * time.Second); err != nil {
+			http.Error(w, "Consistent read failed: "+err.Error(), http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	default:
+		http.Error(w, "consistency must be stale, default or strong", http.StatusBadRequest)
+		return true
+	}
+}
+
+// advertiseAddr turns a listen address such as ":8000" into one other nodes can dial.
+func advertiseAddr(listenAddr string) string {
+	if strings.HasPrefix(listenAddr, ":") {
+		return "localhost" + listenAddr
+	}
+	return listenAddr
+}
@@ -3,16 +3,21 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/hashicorp/raft"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/config"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/master"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
@@ -25,19 +30,43 @@ func main() {
 	nodeID := flag.String("id", "node-1", "Unique ID for this node")
 	raftAddr := flag.String("raft", "localhost:7000", "Address for Raft transport")
 	httpAddr := flag.String("http", ":8000", "Address for HTTP API")
+	advertise := flag.String("advertise", "", "HTTP address other nodes and workers use to reach this node (default: derived from -http)")
 	bootstrap := flag.Bool("bootstrap", false, "Bootstrap the cluster (only for the first node)")
+	joinAddr := flag.String("join", "", "HTTP address of an existing node to join through on startup")
+	nonvoter := flag.Bool("nonvoter", false, "Join as a non-voting worker-only node (requires -join)")
+	leaveOnTerminate := flag.Bool("leave-on-terminate", false, "Leave the cluster gracefully on SIGTERM")
+	configPath := flag.String("config", "", "Path to a JSON config file with runtime settings")
+	overrides := config.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	// Runtime settings: defaults < config file < DISTRAFT_* env < flags
+	cfg, err := config.Load(*configPath, overrides)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	if *bootstrap && *nonvoter {
+		log.Fatalf("A bootstrap node must be a voter; drop -nonvoter")
+	}
+	if *advertise == "" {
+		*advertise = advertiseAddr(*httpAddr)
+	}
+
 	// 2. Setup Data Directory
 	// This is where Raft stores its logs. We create a folder named after the Node ID.
-	raftDir := fmt.Sprintf("raft-data/%s", *nodeID)
+	raftDir := filepath.Join(cfg.DataDir, *nodeID)
 	os.MkdirAll(raftDir, 0700)
 
 	// 3. Initialize the State (The Brain)
 	fsmStore := store.NewState()
 
 	// 4. Initialize Raft (The Engine)
-	rNode, err := consensus.NewRaftNode(*nodeID, *raftAddr, raftDir, fsmStore)
+	rNode, err := consensus.NewRaftNodeWithTiming(*nodeID, *raftAddr, raftDir, fsmStore, consensus.Timing{
+		HeartbeatTimeout:   cfg.Raft.HeartbeatTimeout.Duration,
+		ElectionTimeout:    cfg.Raft.ElectionTimeout.Duration,
+		CommitTimeout:      cfg.Raft.CommitTimeout.Duration,
+		LeaderLeaseTimeout: cfg.Raft.LeaderLeaseTimeout.Duration,
+	})
 	if err != nil {
 		log.Fatalf("Failed to create raft node: %v", err)
 	}
@@ -45,7 +74,7 @@ func main() {
 	// 5. Handle Bootstrap
 	// The first node needs to say "I am the leader" to start the cluster.
 	if *bootstrap {
-		cfg := raft.Configuration{
+		bootstrapCfg := raft.Configuration{
 			Servers: []raft.Server{
 				{
 					ID:      raft.ServerID(*nodeID),
@@ -53,17 +82,40 @@ func main() {
 				},
 			},
 		}
-		f := rNode.Raft.BootstrapCluster(cfg)
+		f := rNode.Raft.BootstrapCluster(bootstrapCfg)
 		if err := f.Error(); err != nil {
 			log.Printf("Bootstrap error (might already be initialized): %v", err)
 		}
 	}
 
-	// 6. Define cluster size (for now, hardcoded to 3 nodes)
-	clusterSize := 3
+	// 6. Keep our HTTP address in the replicated node registry.
+	// Followers look up the leader here to forward writes, so every node
+	// (re-)registers itself whenever it wins an election.
+	go func() {
+		for isLeader := range rNode.Raft.LeaderCh() {
+			if !isLeader {
+				continue
+			}
+			if err := rNode.RegisterSelf(*nodeID, *raftAddr, *advertise); err != nil {
+				log.Printf("⚠️ Failed to register node address: %v", err)
+			}
+		}
+	}()
+
+	// Worker, health monitor and retry settings; handlers need the retry defaults too
+	workerSettings := worker.Settings{
+		JobTimeout:          cfg.JobTimeout.Duration,
+		MaxRetries:          cfg.MaxRetries,
+		HealthCheckInterval: cfg.HealthCheckInterval.Duration,
+		HeartbeatInterval:   cfg.HeartbeatInterval.Duration,
+		RetryBackoffBase:    cfg.RetryBackoffBase.Duration,
+		RetryBackoffCap:     cfg.RetryBackoffCap.Duration,
+		DataDir:             cfg.DataDir,
+	}
 
 	// 7. Define HTTP API Handlers
 	// These allow us to talk to the cluster using curl or Postman.
+	// Writes can land on any node: followers forward them to the leader.
 
 	// Handler: Submit a new Job
 	http.HandleFunc("/submit", func(w http.ResponseWriter, r *http.Request) {
@@ -71,20 +123,56 @@ func main() {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
 
 		var job store.Job
 		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
 			http.Error(w, "Bad request", http.StatusBadRequest)
 			return
 		}
+		// Only saves a proposal: the FSM rejects a taken ID as of the commit
+		_, jobExists := fsmStore.GetJob(job.ID)
+		_, parentExists := fsmStore.GetParent(job.ID)
+		_, trainingExists := fsmStore.GetTraining(job.ID)
+		if jobExists || parentExists || trainingExists {
+			http.Error(w, fmt.Sprintf("Job %s already exists", job.ID), http.StatusConflict)
+			return
+		}
+		if job.RetryPolicy != nil {
+			if err := job.RetryPolicy.Validate(); err != nil {
+				http.Error(w, "Bad retry policy: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if job.Training != nil {
+			if err := job.Training.Validate(); err != nil {
+				http.Error(w, "Bad training config: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Split across the live membership, worker-only nodes included. The list is
+		// recorded in the log entry so every node creates exactly the same sub-jobs.
+		workers, err := rNode.Members()
+		if err != nil {
+			http.Error(w, "Failed to read cluster configuration: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := master.ValidateAggregator(job.Aggregator, len(workers)); err != nil {
+			http.Error(w, "Bad aggregator: "+err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Prepare the command for Raft
 		// Use SUBMIT_PARENT_JOB to automatically split into sub-jobs
 		event := consensus.LogEvent{
-			Type:        consensus.CmdSubmitParentJob,
-			JobID:       job.ID,
-			Data:        &job,
-			ClusterSize: clusterSize,
+			Type:      consensus.CmdSubmitParentJob,
+			JobID:     job.ID,
+			Data:      &job,
+			Workers:   workers,
+			Timestamp: time.Now().Unix(),
 		}
 		eventBytes, _ := json.Marshal(event)
 
@@ -95,42 +183,202 @@ func main() {
 			http.Error(w, "Raft error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		// The FSM's verdict on the entry
+		if err, _ := applyFuture.Response().(error); err != nil {
+			if errors.Is(err, consensus.ErrJobExists) {
+				http.Error(w, err.Error(), http.StatusConflict)
+			} else {
+				http.Error(w, "Submission rejected: "+err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
 
-		w.Write([]byte(fmt.Sprintf("Parent job %s split into %d sub-jobs successfully", job.ID, clusterSize)))
+		if job.Training != nil {
+			w.Write([]byte(fmt.Sprintf("Training run %s started: round 1 of up to %d split into %d sub-jobs", job.ID, job.Training.Rounds, len(workers))))
+			return
+		}
+		w.Write([]byte(fmt.Sprintf("Parent job %s split into %d sub-jobs successfully", job.ID, len(workers))))
 	})
 
 	// Handler: Join Cluster (Add a new node)
 	http.HandleFunc("/join", func(w http.ResponseWriter, r *http.Request) {
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
+
 		query := r.URL.Query()
 		nodeID := query.Get("nodeID")
 		raftAddr := query.Get("raftAddr")
+		httpAddr := query.Get("httpAddr") // Optional: lets followers forward to this node once it leads
+		asNonvoter := query.Get("nonvoter") == "true"
 
 		if nodeID == "" || raftAddr == "" {
 			http.Error(w, "Missing nodeID or raftAddr", http.StatusBadRequest)
 			return
 		}
 
-		log.Printf("Received join request from %s at %s", nodeID, raftAddr)
+		log.Printf("Received join request from %s at %s (nonvoter: %v)", nodeID, raftAddr, asNonvoter)
 
-		// Tell Raft to add this new guy to the voting pool
-		future := rNode.Raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(raftAddr), 0, 0)
+		// Voters grow the quorum; non-voters only receive the replicated log and run jobs
+		var future raft.IndexFuture
+		if asNonvoter {
+			future = rNode.Raft.AddNonvoter(raft.ServerID(nodeID), raft.ServerAddress(raftAddr), 0, 0)
+		} else {
+			future = rNode.Raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(raftAddr), 0, 0)
+		}
 		if err := future.Error(); err != nil {
 			http.Error(w, "Failed to join: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		if httpAddr != "" {
+			if err := rNode.RegisterSelf(nodeID, raftAddr, advertiseAddr(httpAddr)); err != nil {
+				log.Printf("⚠️ Failed to register HTTP address for %s: %v", nodeID, err)
+			}
+		}
+
 		w.Write([]byte("Node joined successfully"))
 	})
 
-	// Handler: Get Job Status (Read from local memory)
+	// leaveCluster asks the leader to remove this node. A leader hands
+	// leadership to another member first, since it cannot remove itself.
+	leaveCluster := func() error {
+		if rNode.IsLeader() {
+			if err := rNode.Raft.LeadershipTransfer().Error(); err != nil {
+				return fmt.Errorf("transfer leadership: %w", err)
+			}
+		}
+		return worker.NewLeaderClient(rNode).Post("/remove?nodeID="+url.QueryEscape(*nodeID), nil)
+	}
+
+	// Handler: Remove a node from the Cluster
+	http.HandleFunc("/remove", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
+
+		removeID := r.URL.Query().Get("nodeID")
+		if removeID == "" {
+			http.Error(w, "Missing nodeID", http.StatusBadRequest)
+			return
+		}
+
+		log.Printf("Received remove request for %s", removeID)
+
+		// The health monitor reassigns whatever the departed node was working on
+		remove := rNode.RemoveNode
+		if removeID == *nodeID {
+			remove = func(string) error { return leaveCluster() }
+		}
+		if err := remove(removeID); err != nil {
+			http.Error(w, "Failed to remove: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte("Node removed successfully"))
+	})
+
+	// Handler: Leave Cluster (this node removes itself)
+	http.HandleFunc("/leave", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := leaveCluster(); err != nil {
+			http.Error(w, "Failed to leave: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Write([]byte("Node left successfully"))
+	})
+
+	// Handler: Get Job, Parent Job or Training Run Status (see prepareRead for the consistency parameter)
 	http.HandleFunc("/job", func(w http.ResponseWriter, r *http.Request) {
+		if prepareRead(w, r, rNode, *nodeID) {
+			return
+		}
+
 		jobID := r.URL.Query().Get("id")
-		job, ok := fsmStore.GetJob(jobID)
-		if !ok {
+		if job, ok := fsmStore.GetJob(jobID); ok {
+			json.NewEncoder(w).Encode(job)
+			return
+		}
+		// A parent job: its sub-jobs, progress and global model
+		if parent, ok := fsmStore.GetParent(jobID); ok {
+			json.NewEncoder(w).Encode(parent)
+			return
+		}
+		// A multi-round training run: its rounds and their metrics
+		if run, ok := fsmStore.GetTraining(jobID); ok {
+			json.NewEncoder(w).Encode(run)
+			return
+		}
+		http.Error(w, "Job not found", http.StatusNotFound)
+	})
+
+	// Handler: Job History (created, claimed, reassigned, failed, ... in commit order)
+	http.HandleFunc("/job/events", func(w http.ResponseWriter, r *http.Request) {
+		if prepareRead(w, r, rNode, *nodeID) {
+			return
+		}
+
+		jobID := r.URL.Query().Get("id")
+		if _, ok := fsmStore.GetJob(jobID); !ok {
 			http.Error(w, "Job not found", http.StatusNotFound)
 			return
 		}
-		json.NewEncoder(w).Encode(job)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"job_id": jobID,
+			"events": fsmStore.GetJobEvents(jobID),
+		})
+	})
+
+	// Handler: List Jobs
+	// Filters: status, worker, parent, type. Paging: limit, cursor (from next_cursor), order=asc|desc.
+	http.HandleFunc("/jobs", func(w http.ResponseWriter, r *http.Request) {
+		if prepareRead(w, r, rNode, *nodeID) {
+			return
+		}
+
+		query := r.URL.Query()
+		q := store.JobQuery{
+			Status:    store.JobStatus(query.Get("status")),
+			WorkerID:  query.Get("worker"),
+			ParentID:  query.Get("parent"),
+			Type:      query.Get("type"),
+			Ascending: query.Get("order") == "asc",
+			Cursor:    query.Get("cursor"),
+		}
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 0 {
+				http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			q.Limit = n
+		}
+
+		jobs, next, err := fsmStore.ListJobs(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":        jobs,
+			"next_cursor": next,
+		})
+	})
+
+	// Handler: Watch job changes as Server-Sent Events
+	// Filters: id or parent. Resume: from=<index>[.<seq>] or the Last-Event-ID header.
+	http.HandleFunc("/watch", func(w http.ResponseWriter, r *http.Request) {
+		serveWatch(w, r, fsmStore)
 	})
 
 	// Handler: Update Job Status (used by workers to report completion)
@@ -140,7 +388,18 @@ func main() {
 			return
 		}
 
-		var update store.Job
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
+
+		// The body is a partial job plus optional expected_status,
+		// expected_worker_id and expected_revision preconditions, and an
+		// optional reason recorded in the job's history
+		var update struct {
+			store.Job
+			consensus.Precondition
+			Reason string `json:"reason"`
+		}
 		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
 			http.Error(w, "Bad request", http.StatusBadRequest)
 			return
@@ -153,48 +412,266 @@ func main() {
 			return
 		}
 
-		// Merge update fields with existing job
-		if update.Status != "" {
-			existingJob.Status = update.Status
+		// A late report from a worker must not bring a cancelled job back to life.
+		// This only saves a proposal: the FSM rejects updates to finished jobs.
+		if existingJob.Status == store.StatusCancelled {
+			http.Error(w, "Job was cancelled", http.StatusConflict)
+			return
+		}
+
+		// A failed attempt goes through the retry policy, exactly like one the
+		// health monitor detects
+		if update.Job.Status == store.StatusFailed {
+			placement, err := worker.CurrentPlacement(fsmStore, rNode)
+			if err != nil {
+				http.Error(w, "Failed to read cluster configuration: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			job, err := worker.ProposeFailure(rNode, existingJob, update.Job.Failure, "", &update.Precondition, placement, workerSettings)
+			if !writeUpdateError(w, err) {
+				w.Header().Set("X-Job-Revision", strconv.FormatUint(job.Revision, 10))
+				w.Write([]byte(fmt.Sprintf("Job failure recorded, job is %s", job.Status)))
+			}
+			return
+		}
+
+		if update.Reason == "" {
+			update.Reason = update.Job.Error
+		}
+
+		// The FSM checks the preconditions against the job as of the commit,
+		// so a report that lost a race with a reassignment is rejected.
+		// The merged copy is only valid for the revision it was read at, so
+		// unless the caller pinned one, the write is guarded by that revision
+		// and merged again if the job changed meanwhile (e.g. a heartbeat).
+		pre := update.Precondition
+		pinned := pre.Revision != 0 || pre.ModifyIndex != 0
+		var job *store.Job
+		var err error
+		for attempt := 1; ; attempt++ {
+			if !pinned {
+				pre.Revision = existingJob.Revision
+			}
+			job, err = rNode.UpdateJob(consensus.LogEvent{
+				Job:          mergeJobUpdate(existingJob, &update.Job),
+				Precondition: &pre,
+				Reason:       update.Reason,
+			}, 5*time.Second)
+			// Only a job that moved on is worth merging into again
+			if pinned || !errors.Is(err, consensus.ErrPreconditionFailed) || job == nil ||
+				job.Revision == pre.Revision || attempt == maxMergeAttempts {
+				break
+			}
+			existingJob = job
+		}
+		if writeUpdateError(w, err) {
+			return
+		}
+
+		w.Header().Set("X-Job-Revision", strconv.FormatUint(job.Revision, 10))
+		w.Write([]byte("Job updated successfully"))
+	})
+
+	// Handler: Claim a Job (a worker must win this before starting the process)
+	http.HandleFunc("/claim", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
+
+		var claim store.Job
+		if err := json.NewDecoder(r.Body).Decode(&claim); err != nil || claim.ID == "" || claim.WorkerID == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
 		}
-		if update.ResultURL != "" {
-			existingJob.ResultURL = update.ResultURL
+
+		event := consensus.LogEvent{
+			Type:       consensus.CmdClaimJob,
+			JobID:      claim.ID,
+			WorkerID:   claim.WorkerID,
+			RetryEpoch: claim.RetryCount,
+			Timestamp:  time.Now().Unix(),
+		}
+		if err := rNode.ApplyEvent(event, 5*time.Second); err != nil {
+			switch {
+			case errors.Is(err, consensus.ErrJobNotFound):
+				http.Error(w, "Job not found", http.StatusNotFound)
+			case errors.Is(err, consensus.ErrClaimConflict):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Raft error: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
 		}
-		if update.StartedAt > 0 {
-			existingJob.StartedAt = update.StartedAt
+
+		w.Write([]byte("Job claimed"))
+	})
+
+	// Handler: Heartbeat from a worker that is still running a job
+	http.HandleFunc("/heartbeat", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
-		if update.UpdatedAt > 0 {
-			existingJob.UpdatedAt = update.UpdatedAt
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
 		}
-		if update.RetryCount > 0 {
-			existingJob.RetryCount = update.RetryCount
+
+		var hb store.Job
+		if err := json.NewDecoder(r.Body).Decode(&hb); err != nil || hb.ID == "" || hb.WorkerID == "" {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			return
 		}
 
-		// Use CmdSetJob for direct updates (no splitting)
 		event := consensus.LogEvent{
-			Type:  consensus.CmdSetJob,
-			JobID: existingJob.ID,
-			Job:   existingJob,
+			Type:      consensus.CmdHeartbeat,
+			JobID:     hb.ID,
+			WorkerID:  hb.WorkerID,
+			Timestamp: time.Now().Unix(),
+		}
+		if err := rNode.ApplyEvent(event, 5*time.Second); err != nil {
+			switch {
+			case errors.Is(err, consensus.ErrJobNotFound):
+				http.Error(w, "Job not found", http.StatusNotFound)
+			case errors.Is(err, consensus.ErrNotAssigned):
+				http.Error(w, err.Error(), http.StatusConflict)
+			default:
+				http.Error(w, "Raft error: "+err.Error(), http.StatusInternalServerError)
+			}
+			return
 		}
-		eventBytes, _ := json.Marshal(event)
 
-		applyFuture := rNode.Raft.Apply(eventBytes, 5*time.Second)
-		if err := applyFuture.Error(); err != nil {
+		w.Write([]byte("OK"))
+	})
+
+	// Handler: Cancel a Job (or every unfinished sub-job of a parent job)
+	http.HandleFunc("/cancel", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
+
+		jobID := r.URL.Query().Get("id")
+		if jobID == "" {
+			http.Error(w, "Missing id", http.StatusBadRequest)
+			return
+		}
+
+		event := consensus.LogEvent{
+			Type:      consensus.CmdCancelJob,
+			JobID:     jobID,
+			Timestamp: time.Now().Unix(),
+		}
+		if err := rNode.ApplyEvent(event, 5*time.Second); err != nil {
+			if errors.Is(err, consensus.ErrJobNotFound) {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
 			http.Error(w, "Raft error: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		w.Write([]byte("Job updated successfully"))
+		w.Write([]byte(fmt.Sprintf("Job %s cancelled", jobID)))
+	})
+
+	// Handler: Dead Letter (jobs that failed for good, with their attempt history)
+	// Filters: worker, parent, type. Paging: limit, cursor, order=asc|desc, as for /jobs.
+	http.HandleFunc("/dead-letter", func(w http.ResponseWriter, r *http.Request) {
+		if prepareRead(w, r, rNode, *nodeID) {
+			return
+		}
+
+		query := r.URL.Query()
+		q := store.JobQuery{
+			WorkerID:  query.Get("worker"),
+			ParentID:  query.Get("parent"),
+			Type:      query.Get("type"),
+			Ascending: query.Get("order") == "asc",
+			Cursor:    query.Get("cursor"),
+		}
+		if limit := query.Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil || n < 0 {
+				http.Error(w, "limit must be a non-negative integer", http.StatusBadRequest)
+				return
+			}
+			q.Limit = n
+		}
+
+		letters, next, err := fsmStore.DeadLetters(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jobs":        letters,
+			"next_cursor": next,
+		})
+	})
+
+	// Handler: Requeue a dead-lettered job (admin)
+	// POST /dead-letter/requeue?id=<job>[&worker=<node>][&reason=<text>]
+	http.HandleFunc("/dead-letter/requeue", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if forwardToLeader(w, r, rNode, *nodeID) {
+			return
+		}
+
+		query := r.URL.Query()
+		job, ok := fsmStore.GetJob(query.Get("id"))
+		if !ok {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		placement, err := worker.CurrentPlacement(fsmStore, rNode)
+		if err != nil {
+			http.Error(w, "Failed to read cluster configuration: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		job, err = worker.RequeueDeadLetter(rNode, job, query.Get("worker"), query.Get("reason"), placement)
+		if errors.Is(err, worker.ErrUnknownWorker) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if writeUpdateError(w, err) {
+			return
+		}
+
+		w.Header().Set("X-Job-Revision", strconv.FormatUint(job.Revision, 10))
+		w.Write([]byte(fmt.Sprintf("Job %s requeued on %s", job.ID, job.WorkerID)))
 	})
 
 	// 8. Start the worker goroutine
-	go worker.RunWorker(fsmStore, *httpAddr, *nodeID, clusterSize)
+	// It resolves the leader's HTTP address from the node registry on every report.
+	go worker.RunWorker(fsmStore, rNode, *nodeID, workerSettings)
 
 	// 9. Start the health monitor (checks for stuck jobs and reassigns them)
-	go worker.RunHealthMonitor(fsmStore, rNode, clusterSize)
-
-	// 10. Start the aggregator (leader-only preferred; harmless on followers)
-	go master.RunAggregator(fsmStore, "", 2*time.Second)
+	go worker.RunHealthMonitor(fsmStore, rNode, workerSettings)
+
+	// 10. Start the aggregator and the round trainer (they only act while this node is the leader)
+	go master.RunAggregator(fsmStore, rNode, cfg.DataDir, "", cfg.AggregatorPollInterval.Duration)
+	go master.RunTrainer(fsmStore, rNode, cfg.AggregatorPollInterval.Duration)
+
+	// Optional gRPC service for streaming models and gradients
+	if cfg.GRPCPort > 0 {
+		grpcServer := worker.NewMLWorkerServer(fsmStore, cfg.GRPCPort, cfg.ModelChunkSize)
+		go func() {
+			if err := grpcServer.StartGRPCServer(cfg.GRPCPort); err != nil {
+				log.Printf("gRPC server error: %v", err)
+			}
+		}()
+	}
 
 	// 11. Setup graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -215,10 +692,35 @@ func main() {
 		}
 	}()
 
+	// Ask an existing member to add us once our HTTP API is up
+	if *joinAddr != "" {
+		go func() {
+			path := fmt.Sprintf("/join?nodeID=%s&raftAddr=%s&httpAddr=%s&nonvoter=%t",
+				url.QueryEscape(*nodeID), url.QueryEscape(*raftAddr), url.QueryEscape(*advertise), *nonvoter)
+			if err := worker.NewLeaderClient(worker.StaticLeader(*joinAddr)).Post(path, nil); err != nil {
+				log.Printf("Failed to join cluster via %s: %v", *joinAddr, err)
+				return
+			}
+			log.Printf("Joined cluster via %s (nonvoter: %v)", *joinAddr, *nonvoter)
+		}()
+	} else if *nonvoter {
+		log.Printf("⚠️ -nonvoter has no effect without -join")
+	}
+
 	// Wait for shutdown signal
-	<-sigChan
+	sig := <-sigChan
 	log.Println("Shutting down gracefully...")
 
+	// Leave before stopping so the cluster shrinks its quorum instead of
+	// waiting on a node that will never come back
+	if *leaveOnTerminate && sig == syscall.SIGTERM {
+		if err := leaveCluster(); err != nil {
+			log.Printf("Failed to leave cluster: %v", err)
+		} else {
+			log.Printf("Left the cluster")
+		}
+	}
+
 	// Shutdown HTTP server
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -233,3 +735,55 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+// maxMergeAttempts bounds how often /update re-merges a partial update into a
+// job that kept changing under it
+const maxMergeAttempts = 3
+
+// mergeJobUpdate returns a copy of job with the fields a partial /update
+// body sets
+func mergeJobUpdate(job *store.Job, update *store.Job) *store.Job {
+	merged := *job
+	if update.Status != "" {
+		merged.Status = update.Status
+	}
+	if update.ResultURL != "" {
+		merged.ResultURL = update.ResultURL
+	}
+	if update.StartedAt > 0 {
+		merged.StartedAt = update.StartedAt
+	}
+	if update.UpdatedAt > 0 {
+		merged.UpdatedAt = update.UpdatedAt
+	}
+	if update.RetryCount > 0 {
+		merged.RetryCount = update.RetryCount
+	}
+	if update.NumSamples > 0 {
+		merged.Loss = update.Loss
+		merged.Accuracy = update.Accuracy
+		merged.NumSamples = update.NumSamples
+	}
+	if update.Error != "" || update.FailureCategory != "" {
+		merged.Error = update.Error
+		merged.ExitCode = update.ExitCode
+		merged.FailureCategory = update.FailureCategory
+	}
+	return &merged
+}
+
+// writeUpdateError maps the error of a conditional job update to an HTTP
+// response. It returns false (and writes nothing) if err is nil.
+func writeUpdateError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, consensus.ErrPreconditionFailed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, consensus.ErrJobNotFound):
+		http.Error(w, "Job not found", http.StatusNotFound)
+	default:
+		http.Error(w, "Raft error: "+err.Error(), http.StatusInternalServerError)
+	}
+	return true
+}
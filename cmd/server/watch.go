@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/worker"
+)
+
+// watchKeepAlive is how often an idle event stream gets a comment line, so
+// proxies and clients do not time the connection out
+const watchKeepAlive = 15 * time.Second
+
+// serveWatch streams job changes as Server-Sent Events. Each event's id is
+// the change's "index.seq" (see store.ChangeID), so a reconnecting client
+// resumes via Last-Event-ID even when one entry changed several jobs.
+//
+//	GET /watch?id=<job> | ?parent=<parent> [&from=<index>[.<seq>]]
+//
+// Any node can serve a watch from its replicated state; a follower's stream
+// simply trails the leader by the replication lag.
+func serveWatch(w http.ResponseWriter, r *http.Request, state *store.State) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	from := q.Get("from")
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		from = last
+	}
+	var after store.ChangeID
+	if from != "" {
+		var err error
+		if after, err = store.ParseChangeID(from); err != nil {
+			http.Error(w, "Invalid from index", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter := store.WatchFilter{JobID: q.Get("id"), ParentID: q.Get("parent")}
+	watcher, err := state.Watch(filter, after, worker.WatchBuffer)
+	if errors.Is(err, store.ErrCompacted) {
+		http.Error(w, err.Error(), http.StatusGone)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer watcher.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(watchKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case change, ok := <-watcher.C:
+			if !ok {
+				// The client has to reconnect (from its last id, or afresh after a restore)
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", watcher.Err())
+				flusher.Flush()
+				return
+			}
+			data, _ := json.Marshal(change.Job)
+			fmt.Fprintf(w, "id: %s\nevent: job\ndata: %s\n\n", change.ID, data)
+		}
+		flusher.Flush()
+	}
+}
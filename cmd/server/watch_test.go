@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// sseEvent is one event read off a /watch stream
+type sseEvent struct {
+	id  string
+	job store.Job
+}
+
+// openWatch starts a /watch request; lastEventID resumes a dropped stream
+func openWatch(t *testing.T, ctx context.Context, url, lastEventID string) *bufio.Scanner {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("watch: status %d", resp.StatusCode)
+	}
+	return bufio.NewScanner(resp.Body)
+}
+
+// nextEvent reads the stream up to the end of its next job event
+func nextEvent(t *testing.T, scanner *bufio.Scanner) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.job); err != nil {
+				t.Fatalf("bad event data %q: %v", line, err)
+			}
+		case line == "" && event.id != "":
+			return event
+		}
+	}
+	t.Fatalf("stream ended: %v", scanner.Err())
+	return event
+}
+
+// TestWatchResumesMidEntry drops an SSE watch after the first of the sub-jobs
+// a parent submission created. All of them share one Raft index, so resuming
+// via Last-Event-ID must pick up the rest of that entry rather than skip it.
+func TestWatchResumesMidEntry(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+	apply := func(index uint64, event consensus.LogEvent) {
+		fsm.Apply(&raft.Log{Index: index, Term: 1, Data: consensus.MustMarshalEvent(event)})
+	}
+	apply(1, consensus.LogEvent{Type: consensus.CmdSetJob, Job: &store.Job{ID: "other", Status: store.StatusPending}})
+	apply(2, consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: "job-1", Job: &store.Job{ID: "job-1"},
+		Workers: []string{"node-1", "node-2", "node-3"}})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serveWatch(w, r, state)
+	}))
+	defer server.Close()
+	url := server.URL + "/watch?parent=job-1&from=1"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	first := nextEvent(t, openWatch(t, ctx, url, ""))
+	cancel() // The connection drops after one event
+	if first.id != "2.0" || first.job.ID != "job-1-node-1" {
+		t.Fatalf("unexpected first event: %+v", first)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resumed := openWatch(t, ctx, url, first.id)
+	for _, want := range []sseEvent{{id: "2.1", job: store.Job{ID: "job-1-node-2"}}, {id: "2.2", job: store.Job{ID: "job-1-node-3"}}} {
+		if got := nextEvent(t, resumed); got.id != want.id || got.job.ID != want.job.ID {
+			t.Fatalf("expected %s (%s) after resuming, got %s (%s)", want.id, want.job.ID, got.id, got.job.ID)
+		}
+	}
+
+	// Live changes follow the replayed ones
+	apply(3, consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1-node-2", WorkerID: "node-2", Timestamp: 10})
+	if got := nextEvent(t, resumed); got.id != "3.0" || got.job.Status != store.StatusRunning {
+		t.Fatalf("unexpected live event: %+v", got)
+	}
+}
@@ -0,0 +1,84 @@
+package aggregation
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Metadata keys written by train.py and by merges
+const (
+	MetaNumSamples = "num_samples" // Training samples behind the model
+	MetaNumModels  = "num_models"  // Models merged into a global model
+	MetaAggregator = "aggregator"  // Strategy that merged a global model
+)
+
+// SampleWeight is the weight of m in FedAvg: the number of samples it was
+// trained on according to its metadata, or 1 if it does not say.
+func SampleWeight(m *Model) float64 {
+	n, err := strconv.ParseFloat(m.Metadata[MetaNumSamples], 64)
+	if err != nil || n <= 0 {
+		return 1
+	}
+	return n
+}
+
+// FedAvg returns the weighted average of models: every tensor of the result
+// is sum(weights[i] * models[i].tensor) / sum(weights). All models must have
+// the same tensors with the same shapes; the result uses the first model's dtypes.
+func FedAvg(models []*Model, weights []float64) (*Model, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to average")
+	}
+	if len(weights) != len(models) {
+		return nil, fmt.Errorf("got %d weights for %d models", len(weights), len(models))
+	}
+	var total float64
+	for i, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("model %d has negative weight %g", i, w)
+		}
+		total += w
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("weights sum to zero")
+	}
+	if err := checkCompatible(models); err != nil {
+		return nil, err
+	}
+
+	avg := &Model{Tensors: make(map[string]*Tensor, len(models[0].Tensors)), Metadata: make(map[string]string)}
+	for name, first := range models[0].Tensors {
+		sum := make([]float64, len(first.Data))
+		for i, m := range models {
+			for j, v := range m.Tensors[name].Data {
+				sum[j] += weights[i] * v
+			}
+		}
+		for j := range sum {
+			sum[j] /= total
+		}
+		avg.Tensors[name] = &Tensor{DType: first.DType, Shape: slices.Clone(first.Shape), Data: sum}
+	}
+	return avg, nil
+}
+
+// checkCompatible verifies that every model has the first model's tensors and shapes
+func checkCompatible(models []*Model) error {
+	first := models[0]
+	for i, m := range models[1:] {
+		if len(m.Tensors) != len(first.Tensors) {
+			return fmt.Errorf("model %d has %d tensors, model 0 has %d", i+1, len(m.Tensors), len(first.Tensors))
+		}
+		for name, t := range first.Tensors {
+			other, ok := m.Tensors[name]
+			if !ok {
+				return fmt.Errorf("model %d has no tensor %s", i+1, name)
+			}
+			if !slices.Equal(other.Shape, t.Shape) {
+				return fmt.Errorf("tensor %s: model %d has shape %v, model 0 has %v", name, i+1, other.Shape, t.Shape)
+			}
+		}
+	}
+	return nil
+}
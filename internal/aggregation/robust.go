@@ -0,0 +1,128 @@
+package aggregation
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// Median returns the coordinate-wise median of models: every element of the
+// result is the median of that element across the models (the mean of the two
+// middle values for an even count). A single poisoned model cannot drag it far.
+func Median(models []*Model) (*Model, error) {
+	return coordinateWise(models, func(values []float64) float64 {
+		mid := len(values) / 2
+		if len(values)%2 == 1 {
+			return values[mid]
+		}
+		return (values[mid-1] + values[mid]) / 2
+	})
+}
+
+// TrimmedMean returns the coordinate-wise trimmed mean of models: for every
+// element, the trim largest and trim smallest values are dropped and the rest
+// averaged. At least one value must remain.
+func TrimmedMean(models []*Model, trim int) (*Model, error) {
+	if trim < 0 || len(models)-2*trim < 1 {
+		return nil, fmt.Errorf("cannot trim %d models from each end of %d", trim, len(models))
+	}
+	return coordinateWise(models, func(values []float64) float64 {
+		var sum float64
+		kept := values[trim : len(values)-trim]
+		for _, v := range kept {
+			sum += v
+		}
+		return sum / float64(len(kept))
+	})
+}
+
+// coordinateWise builds a model whose every element is reduce of the sorted
+// values of that element across models
+func coordinateWise(models []*Model, reduce func(sorted []float64) float64) (*Model, error) {
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no models to aggregate")
+	}
+	if err := checkCompatible(models); err != nil {
+		return nil, err
+	}
+
+	out := &Model{Tensors: make(map[string]*Tensor, len(models[0].Tensors)), Metadata: make(map[string]string)}
+	values := make([]float64, len(models))
+	for name, first := range models[0].Tensors {
+		data := make([]float64, len(first.Data))
+		for j := range data {
+			for i, m := range models {
+				values[i] = m.Tensors[name].Data[j]
+			}
+			slices.Sort(values)
+			data[j] = reduce(values)
+		}
+		out.Tensors[name] = &Tensor{DType: first.DType, Shape: slices.Clone(first.Shape), Data: data}
+	}
+	return out, nil
+}
+
+// Krum returns the index of the model chosen by Krum (Blanchard et al., 2017)
+// tolerating byzantine faulty models: the one with the smallest sum of squared
+// distances to its len(models)-byzantine-2 nearest neighbours, i.e. the model
+// most in line with the majority. It needs len(models) >= 2*byzantine+3.
+func Krum(models []*Model, byzantine int) (int, error) {
+	n := len(models)
+	if byzantine < 0 || n < 2*byzantine+3 {
+		return 0, fmt.Errorf("krum needs at least %d models to tolerate %d faulty ones, got %d", 2*byzantine+3, byzantine, n)
+	}
+	if err := checkCompatible(models); err != nil {
+		return 0, err
+	}
+
+	dist := make([][]float64, n)
+	for i := range dist {
+		dist[i] = make([]float64, n)
+	}
+	for i := range n {
+		for j := i + 1; j < n; j++ {
+			d := squaredDistance(models[i], models[j])
+			dist[i][j], dist[j][i] = d, d
+		}
+	}
+
+	neighbours := n - byzantine - 2
+	best, bestScore := 0, math.Inf(1)
+	for i := range n {
+		others := make([]float64, 0, n-1)
+		for j := range n {
+			if j != i {
+				others = append(others, dist[i][j])
+			}
+		}
+		slices.Sort(others)
+		var score float64
+		for _, d := range others[:neighbours] {
+			score += d
+		}
+		if score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best, nil
+}
+
+// Distance returns the L2 norm of the difference between two models with the
+// same tensors, e.g. how far a round of training moved a global model
+func Distance(a, b *Model) (float64, error) {
+	if err := checkCompatible([]*Model{a, b}); err != nil {
+		return 0, err
+	}
+	return math.Sqrt(squaredDistance(a, b)), nil
+}
+
+func squaredDistance(a, b *Model) float64 {
+	var sum float64
+	for name, t := range a.Tensors {
+		for j, v := range t.Data {
+			d := v - b.Tensors[name].Data[j]
+			sum += d * d
+		}
+	}
+	return sum
+}
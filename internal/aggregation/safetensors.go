@@ -0,0 +1,255 @@
+// Package aggregation merges the models trained by a parent job's sub-jobs.
+//
+// Models are exchanged as safetensors files
+// (https://huggingface.co/docs/safetensors): an 8-byte little-endian header
+// length, a JSON header mapping each tensor name to its dtype, shape and byte
+// range, and the raw little-endian tensor data. The optional "__metadata__"
+// header entry holds string key/value pairs; train.py records the number of
+// training samples there (see SampleWeight).
+package aggregation
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+)
+
+// DType is a tensor element type, named as in safetensors headers
+type DType string
+
+const (
+	F32 DType = "F32"
+	F64 DType = "F64"
+)
+
+// size returns the number of bytes per element, or 0 for unsupported types
+func (d DType) size() int {
+	switch d {
+	case F32:
+		return 4
+	case F64:
+		return 8
+	}
+	return 0
+}
+
+// maxHeaderSize bounds the JSON header, so a corrupt length cannot make us allocate gigabytes
+const maxHeaderSize = 100 << 20
+
+var (
+	// ErrUnsupportedDType is returned for tensors that are not F32 or F64
+	ErrUnsupportedDType = errors.New("unsupported tensor dtype")
+	// ErrMalformed is returned for files that are not valid safetensors
+	ErrMalformed = errors.New("malformed safetensors file")
+)
+
+// Tensor is a dense tensor. Data holds the elements in row-major order,
+// widened to float64 whatever DType they are stored as.
+type Tensor struct {
+	DType DType
+	Shape []int
+	Data  []float64
+}
+
+// Model is a set of named tensors plus free-form metadata
+type Model struct {
+	Tensors  map[string]*Tensor
+	Metadata map[string]string
+}
+
+// Clone returns a deep copy of m
+func (m *Model) Clone() *Model {
+	c := &Model{Tensors: make(map[string]*Tensor, len(m.Tensors)), Metadata: make(map[string]string, len(m.Metadata))}
+	for name, t := range m.Tensors {
+		c.Tensors[name] = &Tensor{DType: t.DType, Shape: slices.Clone(t.Shape), Data: slices.Clone(t.Data)}
+	}
+	for k, v := range m.Metadata {
+		c.Metadata[k] = v
+	}
+	return c
+}
+
+// headerEntry describes one tensor in the safetensors header
+type headerEntry struct {
+	DType       DType    `json:"dtype"`
+	Shape       []int    `json:"shape"`
+	DataOffsets [2]int64 `json:"data_offsets"` // Relative to the start of the data section
+}
+
+// ReadFile reads a safetensors model from path
+func ReadFile(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Decode parses a safetensors file held in memory
+func Decode(data []byte) (*Model, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrMalformed, len(data))
+	}
+	headerSize := binary.LittleEndian.Uint64(data[:8])
+	if headerSize > maxHeaderSize || headerSize > uint64(len(data)-8) {
+		return nil, fmt.Errorf("%w: header of %d bytes", ErrMalformed, headerSize)
+	}
+	body := data[8+headerSize:]
+
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(data[8:8+headerSize], &header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+
+	m := &Model{Tensors: make(map[string]*Tensor), Metadata: make(map[string]string)}
+	for name, raw := range header {
+		if name == "__metadata__" {
+			if err := json.Unmarshal(raw, &m.Metadata); err != nil {
+				return nil, fmt.Errorf("%w: metadata: %v", ErrMalformed, err)
+			}
+			continue
+		}
+		var entry headerEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("%w: tensor %s: %v", ErrMalformed, name, err)
+		}
+		t, err := decodeTensor(entry, body)
+		if err != nil {
+			return nil, fmt.Errorf("tensor %s: %w", name, err)
+		}
+		m.Tensors[name] = t
+	}
+	return m, nil
+}
+
+func decodeTensor(entry headerEntry, body []byte) (*Tensor, error) {
+	size := entry.DType.size()
+	if size == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDType, entry.DType)
+	}
+	n, err := numElements(entry.Shape)
+	if err != nil {
+		return nil, err
+	}
+	begin, end := entry.DataOffsets[0], entry.DataOffsets[1]
+	if begin < 0 || end < begin || end > int64(len(body)) || end-begin != int64(n*size) {
+		return nil, fmt.Errorf("%w: data offsets %v for %d %s elements", ErrMalformed, entry.DataOffsets, n, entry.DType)
+	}
+
+	raw := body[begin:end]
+	t := &Tensor{DType: entry.DType, Shape: entry.Shape, Data: make([]float64, n)}
+	for i := range t.Data {
+		switch entry.DType {
+		case F32:
+			t.Data[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw[i*4:])))
+		case F64:
+			t.Data[i] = math.Float64frombits(binary.LittleEndian.Uint64(raw[i*8:]))
+		}
+	}
+	return t, nil
+}
+
+func numElements(shape []int) (int, error) {
+	n := 1
+	for _, d := range shape {
+		if d < 0 || (d > 0 && n > math.MaxInt32/d) {
+			return 0, fmt.Errorf("%w: bad shape %v", ErrMalformed, shape)
+		}
+		n *= d
+	}
+	return n, nil
+}
+
+// WriteFile writes m to path in safetensors format. The file is written
+// under a temporary name and renamed into place, so readers never see it
+// half-written.
+func WriteFile(path string, m *Model) error {
+	data, err := Encode(m)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // A no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Encode serializes m in safetensors format. Tensors are laid out in name
+// order, so the same model always encodes to the same bytes.
+func Encode(m *Model) ([]byte, error) {
+	names := make([]string, 0, len(m.Tensors))
+	for name := range m.Tensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := make(map[string]interface{}, len(names)+1)
+	if len(m.Metadata) > 0 {
+		header["__metadata__"] = m.Metadata
+	}
+	var body bytes.Buffer
+	for _, name := range names {
+		t := m.Tensors[name]
+		size := t.DType.size()
+		if size == 0 {
+			return nil, fmt.Errorf("tensor %s: %w: %s", name, ErrUnsupportedDType, t.DType)
+		}
+		if n, err := numElements(t.Shape); err != nil || n != len(t.Data) {
+			return nil, fmt.Errorf("tensor %s: shape %v does not match %d elements", name, t.Shape, len(t.Data))
+		}
+
+		begin := int64(body.Len())
+		buf := make([]byte, size)
+		for _, v := range t.Data {
+			switch t.DType {
+			case F32:
+				binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(v)))
+			case F64:
+				binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+			}
+			body.Write(buf)
+		}
+		shape := t.Shape
+		if shape == nil {
+			shape = []int{} // A scalar; the header needs [] rather than null
+		}
+		header[name] = headerEntry{DType: t.DType, Shape: shape, DataOffsets: [2]int64{begin, int64(body.Len())}}
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	// The format recommends padding the header with spaces to an 8-byte boundary
+	if pad := len(headerJSON) % 8; pad != 0 {
+		headerJSON = append(headerJSON, bytes.Repeat([]byte(" "), 8-pad)...)
+	}
+
+	out := make([]byte, 8, 8+len(headerJSON)+body.Len())
+	binary.LittleEndian.PutUint64(out, uint64(len(headerJSON)))
+	out = append(out, headerJSON...)
+	return append(out, body.Bytes()...), nil
+}
@@ -213,6 +213,380 @@ func (*ModelRequest) Descriptor() ([]byte, []int) {
 	return file_internal_api_ml_service_proto_rawDescGZIP(), []int{3}
 }
 
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`              // Only changes to this job
+	ParentId      string                 `protobuf:"bytes,2,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`     // Only changes to sub-jobs of this parent
+	FromIndex     uint64                 `protobuf:"varint,3,opt,name=from_index,json=fromIndex,proto3" json:"from_index,omitempty"` // Replay changes committed after this Raft index (0 = only new changes)
+	After         string                 `protobuf:"bytes,4,opt,name=after,proto3" json:"after,omitempty"`                           // Replay changes after this JobChange.id instead, to resume a dropped watch
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_internal_api_ml_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_ml_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_internal_api_ml_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *WatchRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetFromIndex() uint64 {
+	if x != nil {
+		return x.FromIndex
+	}
+	return 0
+}
+
+func (x *WatchRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+// JobChange is one committed change to a job
+type JobChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // "index.seq": Raft index of the entry and position among its changes
+	Job           *Job                   `protobuf:"bytes,2,opt,name=job,proto3" json:"job,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobChange) Reset() {
+	*x = JobChange{}
+	mi := &file_internal_api_ml_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobChange) ProtoMessage() {}
+
+func (x *JobChange) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_ml_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobChange.ProtoReflect.Descriptor instead.
+func (*JobChange) Descriptor() ([]byte, []int) {
+	return file_internal_api_ml_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *JobChange) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *JobChange) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+// Job mirrors store.Job
+type Job struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type            string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Status          string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	WorkerId        string                 `protobuf:"bytes,4,opt,name=worker_id,json=workerId,proto3" json:"worker_id,omitempty"`
+	ResultUrl       string                 `protobuf:"bytes,5,opt,name=result_url,json=resultUrl,proto3" json:"result_url,omitempty"`
+	StartedAt       int64                  `protobuf:"varint,6,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	UpdatedAt       int64                  `protobuf:"varint,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	RetryCount      int32                  `protobuf:"varint,8,opt,name=retry_count,json=retryCount,proto3" json:"retry_count,omitempty"`
+	LastHeartbeat   int64                  `protobuf:"varint,9,opt,name=last_heartbeat,json=lastHeartbeat,proto3" json:"last_heartbeat,omitempty"`
+	ParentId        string                 `protobuf:"bytes,10,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	ShardIndex      int32                  `protobuf:"varint,11,opt,name=shard_index,json=shardIndex,proto3" json:"shard_index,omitempty"`
+	TotalShards     int32                  `protobuf:"varint,12,opt,name=total_shards,json=totalShards,proto3" json:"total_shards,omitempty"`
+	Revision        uint64                 `protobuf:"varint,13,opt,name=revision,proto3" json:"revision,omitempty"`
+	CreateIndex     uint64                 `protobuf:"varint,14,opt,name=create_index,json=createIndex,proto3" json:"create_index,omitempty"`
+	CreateTerm      uint64                 `protobuf:"varint,15,opt,name=create_term,json=createTerm,proto3" json:"create_term,omitempty"`
+	ModifyIndex     uint64                 `protobuf:"varint,16,opt,name=modify_index,json=modifyIndex,proto3" json:"modify_index,omitempty"`
+	ModifyTerm      uint64                 `protobuf:"varint,17,opt,name=modify_term,json=modifyTerm,proto3" json:"modify_term,omitempty"`
+	Error           string                 `protobuf:"bytes,18,opt,name=error,proto3" json:"error,omitempty"`
+	ExitCode        int32                  `protobuf:"varint,19,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	FailureCategory string                 `protobuf:"bytes,20,opt,name=failure_category,json=failureCategory,proto3" json:"failure_category,omitempty"`
+	NotBefore       int64                  `protobuf:"varint,21,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	Requeues        int32                  `protobuf:"varint,22,opt,name=requeues,proto3" json:"requeues,omitempty"`
+	RequeuedAtRetry int32                  `protobuf:"varint,23,opt,name=requeued_at_retry,json=requeuedAtRetry,proto3" json:"requeued_at_retry,omitempty"`
+	InitialModel    string                 `protobuf:"bytes,24,opt,name=initial_model,json=initialModel,proto3" json:"initial_model,omitempty"`
+	Loss            float64                `protobuf:"fixed64,25,opt,name=loss,proto3" json:"loss,omitempty"`
+	Accuracy        float64                `protobuf:"fixed64,26,opt,name=accuracy,proto3" json:"accuracy,omitempty"`
+	NumSamples      int32                  `protobuf:"varint,27,opt,name=num_samples,json=numSamples,proto3" json:"num_samples,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_internal_api_ml_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_api_ml_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_internal_api_ml_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Job) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Job) GetWorkerId() string {
+	if x != nil {
+		return x.WorkerId
+	}
+	return ""
+}
+
+func (x *Job) GetResultUrl() string {
+	if x != nil {
+		return x.ResultUrl
+	}
+	return ""
+}
+
+func (x *Job) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *Job) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *Job) GetRetryCount() int32 {
+	if x != nil {
+		return x.RetryCount
+	}
+	return 0
+}
+
+func (x *Job) GetLastHeartbeat() int64 {
+	if x != nil {
+		return x.LastHeartbeat
+	}
+	return 0
+}
+
+func (x *Job) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+func (x *Job) GetShardIndex() int32 {
+	if x != nil {
+		return x.ShardIndex
+	}
+	return 0
+}
+
+func (x *Job) GetTotalShards() int32 {
+	if x != nil {
+		return x.TotalShards
+	}
+	return 0
+}
+
+func (x *Job) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Job) GetCreateIndex() uint64 {
+	if x != nil {
+		return x.CreateIndex
+	}
+	return 0
+}
+
+func (x *Job) GetCreateTerm() uint64 {
+	if x != nil {
+		return x.CreateTerm
+	}
+	return 0
+}
+
+func (x *Job) GetModifyIndex() uint64 {
+	if x != nil {
+		return x.ModifyIndex
+	}
+	return 0
+}
+
+func (x *Job) GetModifyTerm() uint64 {
+	if x != nil {
+		return x.ModifyTerm
+	}
+	return 0
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetExitCode() int32 {
+	if x != nil {
+		return x.ExitCode
+	}
+	return 0
+}
+
+func (x *Job) GetFailureCategory() string {
+	if x != nil {
+		return x.FailureCategory
+	}
+	return ""
+}
+
+func (x *Job) GetNotBefore() int64 {
+	if x != nil {
+		return x.NotBefore
+	}
+	return 0
+}
+
+func (x *Job) GetRequeues() int32 {
+	if x != nil {
+		return x.Requeues
+	}
+	return 0
+}
+
+func (x *Job) GetRequeuedAtRetry() int32 {
+	if x != nil {
+		return x.RequeuedAtRetry
+	}
+	return 0
+}
+
+func (x *Job) GetInitialModel() string {
+	if x != nil {
+		return x.InitialModel
+	}
+	return ""
+}
+
+func (x *Job) GetLoss() float64 {
+	if x != nil {
+		return x.Loss
+	}
+	return 0
+}
+
+func (x *Job) GetAccuracy() float64 {
+	if x != nil {
+		return x.Accuracy
+	}
+	return 0
+}
+
+func (x *Job) GetNumSamples() int32 {
+	if x != nil {
+		return x.NumSamples
+	}
+	return 0
+}
+
 var File_internal_api_ml_service_proto protoreflect.FileDescriptor
 
 const file_internal_api_ml_service_proto_rawDesc = "" +
@@ -228,10 +602,58 @@ const file_internal_api_ml_service_proto_rawDesc = "" +
 	"\x04data\x18\x03 \x01(\fR\x04data\"\x1f\n" +
 	"\x03Ack\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x0e\n" +
-	"\fModelRequest2t\n" +
+	"\fModelRequest\"w\n" +
+	"\fWatchRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12\x1b\n" +
+	"\tparent_id\x18\x02 \x01(\tR\bparentId\x12\x1d\n" +
+	"\n" +
+	"from_index\x18\x03 \x01(\x04R\tfromIndex\x12\x14\n" +
+	"\x05after\x18\x04 \x01(\tR\x05after\"7\n" +
+	"\tJobChange\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\x03job\x18\x02 \x01(\v2\b.api.JobR\x03job\"\xc3\x06\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x1b\n" +
+	"\tworker_id\x18\x04 \x01(\tR\bworkerId\x12\x1d\n" +
+	"\n" +
+	"result_url\x18\x05 \x01(\tR\tresultUrl\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x06 \x01(\x03R\tstartedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\x03R\tupdatedAt\x12\x1f\n" +
+	"\vretry_count\x18\b \x01(\x05R\n" +
+	"retryCount\x12%\n" +
+	"\x0elast_heartbeat\x18\t \x01(\x03R\rlastHeartbeat\x12\x1b\n" +
+	"\tparent_id\x18\n" +
+	" \x01(\tR\bparentId\x12\x1f\n" +
+	"\vshard_index\x18\v \x01(\x05R\n" +
+	"shardIndex\x12!\n" +
+	"\ftotal_shards\x18\f \x01(\x05R\vtotalShards\x12\x1a\n" +
+	"\brevision\x18\r \x01(\x04R\brevision\x12!\n" +
+	"\fcreate_index\x18\x0e \x01(\x04R\vcreateIndex\x12\x1f\n" +
+	"\vcreate_term\x18\x0f \x01(\x04R\n" +
+	"createTerm\x12!\n" +
+	"\fmodify_index\x18\x10 \x01(\x04R\vmodifyIndex\x12\x1f\n" +
+	"\vmodify_term\x18\x11 \x01(\x04R\n" +
+	"modifyTerm\x12\x14\n" +
+	"\x05error\x18\x12 \x01(\tR\x05error\x12\x1b\n" +
+	"\texit_code\x18\x13 \x01(\x05R\bexitCode\x12)\n" +
+	"\x10failure_category\x18\x14 \x01(\tR\x0ffailureCategory\x12\x1d\n" +
+	"\n" +
+	"not_before\x18\x15 \x01(\x03R\tnotBefore\x12\x1a\n" +
+	"\brequeues\x18\x16 \x01(\x05R\brequeues\x12*\n" +
+	"\x11requeued_at_retry\x18\x17 \x01(\x05R\x0frequeuedAtRetry\x12#\n" +
+	"\rinitial_model\x18\x18 \x01(\tR\finitialModel\x12\x12\n" +
+	"\x04loss\x18\x19 \x01(\x01R\x04loss\x12\x1a\n" +
+	"\baccuracy\x18\x1a \x01(\x01R\baccuracy\x12\x1f\n" +
+	"\vnum_samples\x18\x1b \x01(\x05R\n" +
+	"numSamples2\xa6\x01\n" +
 	"\x0fMLWorkerService\x120\n" +
 	"\bGetModel\x12\x11.api.ModelRequest\x1a\x0f.api.ModelChunk0\x01\x12/\n" +
-	"\rSendGradients\x12\x12.api.GradientChunk\x1a\b.api.Ack(\x01B3Z1github.com/yourusername/raft-ml-grid/internal/apib\x06proto3"
+	"\rSendGradients\x12\x12.api.GradientChunk\x1a\b.api.Ack(\x01\x120\n" +
+	"\tWatchJobs\x12\x11.api.WatchRequest\x1a\x0e.api.JobChange0\x01B8Z6github.com/vigneshSrinivasan2005/DistRAFT/internal/apib\x06proto3"
 
 var (
 	file_internal_api_ml_service_proto_rawDescOnce sync.Once
@@ -245,23 +667,29 @@ func file_internal_api_ml_service_proto_rawDescGZIP() []byte {
 	return file_internal_api_ml_service_proto_rawDescData
 }
 
-var file_internal_api_ml_service_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_internal_api_ml_service_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_internal_api_ml_service_proto_goTypes = []any{
 	(*ModelChunk)(nil),    // 0: api.ModelChunk
 	(*GradientChunk)(nil), // 1: api.GradientChunk
 	(*Ack)(nil),           // 2: api.Ack
 	(*ModelRequest)(nil),  // 3: api.ModelRequest
+	(*WatchRequest)(nil),  // 4: api.WatchRequest
+	(*JobChange)(nil),     // 5: api.JobChange
+	(*Job)(nil),           // 6: api.Job
 }
 var file_internal_api_ml_service_proto_depIdxs = []int32{
-	3, // 0: api.MLWorkerService.GetModel:input_type -> api.ModelRequest
-	1, // 1: api.MLWorkerService.SendGradients:input_type -> api.GradientChunk
-	0, // 2: api.MLWorkerService.GetModel:output_type -> api.ModelChunk
-	2, // 3: api.MLWorkerService.SendGradients:output_type -> api.Ack
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	6, // 0: api.JobChange.job:type_name -> api.Job
+	3, // 1: api.MLWorkerService.GetModel:input_type -> api.ModelRequest
+	1, // 2: api.MLWorkerService.SendGradients:input_type -> api.GradientChunk
+	4, // 3: api.MLWorkerService.WatchJobs:input_type -> api.WatchRequest
+	0, // 4: api.MLWorkerService.GetModel:output_type -> api.ModelChunk
+	2, // 5: api.MLWorkerService.SendGradients:output_type -> api.Ack
+	5, // 6: api.MLWorkerService.WatchJobs:output_type -> api.JobChange
+	4, // [4:7] is the sub-list for method output_type
+	1, // [1:4] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_internal_api_ml_service_proto_init() }
@@ -275,7 +703,7 @@ func file_internal_api_ml_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_api_ml_service_proto_rawDesc), len(file_internal_api_ml_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -21,6 +21,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	MLWorkerService_GetModel_FullMethodName      = "/api.MLWorkerService/GetModel"
 	MLWorkerService_SendGradients_FullMethodName = "/api.MLWorkerService/SendGradients"
+	MLWorkerService_WatchJobs_FullMethodName     = "/api.MLWorkerService/WatchJobs"
 )
 
 // MLWorkerServiceClient is the client API for MLWorkerService service.
@@ -29,6 +30,8 @@ const (
 type MLWorkerServiceClient interface {
 	GetModel(ctx context.Context, in *ModelRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ModelChunk], error)
 	SendGradients(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[GradientChunk, Ack], error)
+	// WatchJobs streams every committed change to the selected jobs
+	WatchJobs(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobChange], error)
 }
 
 type mLWorkerServiceClient struct {
@@ -71,12 +74,33 @@ func (c *mLWorkerServiceClient) SendGradients(ctx context.Context, opts ...grpc.
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MLWorkerService_SendGradientsClient = grpc.ClientStreamingClient[GradientChunk, Ack]
 
+func (c *mLWorkerServiceClient) WatchJobs(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[JobChange], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MLWorkerService_ServiceDesc.Streams[2], MLWorkerService_WatchJobs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRequest, JobChange]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MLWorkerService_WatchJobsClient = grpc.ServerStreamingClient[JobChange]
+
 // MLWorkerServiceServer is the server API for MLWorkerService service.
 // All implementations must embed UnimplementedMLWorkerServiceServer
 // for forward compatibility.
 type MLWorkerServiceServer interface {
 	GetModel(*ModelRequest, grpc.ServerStreamingServer[ModelChunk]) error
 	SendGradients(grpc.ClientStreamingServer[GradientChunk, Ack]) error
+	// WatchJobs streams every committed change to the selected jobs
+	WatchJobs(*WatchRequest, grpc.ServerStreamingServer[JobChange]) error
 	mustEmbedUnimplementedMLWorkerServiceServer()
 }
 
@@ -93,6 +117,9 @@ func (UnimplementedMLWorkerServiceServer) GetModel(*ModelRequest, grpc.ServerStr
 func (UnimplementedMLWorkerServiceServer) SendGradients(grpc.ClientStreamingServer[GradientChunk, Ack]) error {
 	return status.Error(codes.Unimplemented, "method SendGradients not implemented")
 }
+func (UnimplementedMLWorkerServiceServer) WatchJobs(*WatchRequest, grpc.ServerStreamingServer[JobChange]) error {
+	return status.Error(codes.Unimplemented, "method WatchJobs not implemented")
+}
 func (UnimplementedMLWorkerServiceServer) mustEmbedUnimplementedMLWorkerServiceServer() {}
 func (UnimplementedMLWorkerServiceServer) testEmbeddedByValue()                         {}
 
@@ -132,6 +159,17 @@ func _MLWorkerService_SendGradients_Handler(srv interface{}, stream grpc.ServerS
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type MLWorkerService_SendGradientsServer = grpc.ClientStreamingServer[GradientChunk, Ack]
 
+func _MLWorkerService_WatchJobs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MLWorkerServiceServer).WatchJobs(m, &grpc.GenericServerStream[WatchRequest, JobChange]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MLWorkerService_WatchJobsServer = grpc.ServerStreamingServer[JobChange]
+
 // MLWorkerService_ServiceDesc is the grpc.ServiceDesc for MLWorkerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -150,6 +188,11 @@ var MLWorkerService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _MLWorkerService_SendGradients_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WatchJobs",
+			Handler:       _MLWorkerService_WatchJobs_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "internal/api/ml_service.proto",
 }
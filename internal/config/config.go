@@ -0,0 +1,281 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix is prepended to every setting name to form its environment variable,
+// e.g. job-timeout -> DISTRAFT_JOB_TIMEOUT
+const EnvPrefix = "DISTRAFT_"
+
+// Config holds the runtime settings of a node.
+// Precedence (lowest to highest): defaults, config file, environment, flags.
+type Config struct {
+	DataDir string `json:"data_dir"` // Models go in <data_dir>, Raft logs and snapshots in <data_dir>/<node-id>
+
+	JobTimeout          Duration `json:"job_timeout"`           // Running jobs without a heartbeat for this long are stuck
+	MaxRetries          int      `json:"max_retries"`           // Default retries before a job is marked FAILED
+	HealthCheckInterval Duration `json:"health_check_interval"` // How often the leader looks for stuck jobs
+	HeartbeatInterval   Duration `json:"heartbeat_interval"`    // How often workers heartbeat running jobs
+	RetryBackoffBase    Duration `json:"retry_backoff_base"`    // Default delay before the first retry, doubling per retry
+	RetryBackoffCap     Duration `json:"retry_backoff_cap"`     // Default upper bound on the retry delay
+
+	AggregatorPollInterval Duration `json:"aggregator_poll_interval"`
+
+	GRPCPort       int `json:"grpc_port"`        // Port for the model/gradient gRPC service (0 disables it)
+	ModelChunkSize int `json:"model_chunk_size"` // Bytes per streamed model chunk
+
+	Raft RaftTiming `json:"raft"`
+}
+
+// RaftTiming tunes hashicorp/raft. See raft.Config for the meaning of each value.
+type RaftTiming struct {
+	HeartbeatTimeout   Duration `json:"heartbeat_timeout"`
+	ElectionTimeout    Duration `json:"election_timeout"`
+	CommitTimeout      Duration `json:"commit_timeout"`
+	LeaderLeaseTimeout Duration `json:"leader_lease_timeout"`
+}
+
+// Default returns the settings the cluster used before they became configurable
+func Default() Config {
+	return Config{
+		DataDir:                "raft-data",
+		JobTimeout:             Duration{15 * time.Second},
+		MaxRetries:             2,
+		HealthCheckInterval:    Duration{5 * time.Second},
+		HeartbeatInterval:      Duration{5 * time.Second},
+		RetryBackoffBase:       Duration{5 * time.Second},
+		RetryBackoffCap:        Duration{60 * time.Second},
+		AggregatorPollInterval: Duration{2 * time.Second},
+		GRPCPort:               0,
+		ModelChunkSize:         1024 * 1024,
+		Raft: RaftTiming{
+			HeartbeatTimeout:   Duration{1000 * time.Millisecond},
+			ElectionTimeout:    Duration{1000 * time.Millisecond},
+			CommitTimeout:      Duration{50 * time.Millisecond},
+			LeaderLeaseTimeout: Duration{500 * time.Millisecond},
+		},
+	}
+}
+
+// LoadFile overlays the JSON config file at path onto c. Unknown keys are an error
+// so that a typo does not silently fall back to a default.
+func (c *Config) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// ApplyEnv overlays DISTRAFT_* environment variables onto c
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, s := range settings {
+		if v, ok := lookup(EnvName(s.name)); ok {
+			if err := s.set(c, v); err != nil {
+				return fmt.Errorf("%s: %w", EnvName(s.name), err)
+			}
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable that overrides a setting
+func EnvName(setting string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// Flags holds override flags registered by BindFlags
+type Flags struct {
+	fs     *flag.FlagSet
+	values map[string]*string
+}
+
+// BindFlags registers one override flag per setting on fs.
+// Call Apply after fs.Parse; only flags given on the command line take effect.
+func BindFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs, values: make(map[string]*string)}
+	for _, s := range settings {
+		f.values[s.name] = fs.String(s.name, "", s.usage+" (env "+EnvName(s.name)+")")
+	}
+	return f
+}
+
+// Apply overlays the flags that were set onto c
+func (f *Flags) Apply(c *Config) error {
+	var err error
+	f.fs.Visit(func(fl *flag.Flag) {
+		v, ok := f.values[fl.Name]
+		if !ok || err != nil {
+			return
+		}
+		for _, s := range settings {
+			if s.name == fl.Name {
+				if setErr := s.set(c, *v); setErr != nil {
+					err = fmt.Errorf("-%s: %w", fl.Name, setErr)
+				}
+			}
+		}
+	})
+	return err
+}
+
+// Validate rejects settings the cluster cannot run with
+func (c *Config) Validate() error {
+	var errs []error
+	if c.DataDir == "" {
+		errs = append(errs, errors.New("data_dir must not be empty"))
+	}
+	for name, d := range map[string]Duration{
+		"job_timeout":               c.JobTimeout,
+		"health_check_interval":     c.HealthCheckInterval,
+		"heartbeat_interval":        c.HeartbeatInterval,
+		"retry_backoff_base":        c.RetryBackoffBase,
+		"retry_backoff_cap":         c.RetryBackoffCap,
+		"aggregator_poll_interval":  c.AggregatorPollInterval,
+		"raft.heartbeat_timeout":    c.Raft.HeartbeatTimeout,
+		"raft.election_timeout":     c.Raft.ElectionTimeout,
+		"raft.commit_timeout":       c.Raft.CommitTimeout,
+		"raft.leader_lease_timeout": c.Raft.LeaderLeaseTimeout,
+	} {
+		if d.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", name, d))
+		}
+	}
+	// Jobs record heartbeats and retry times as Unix seconds
+	for name, d := range map[string]Duration{
+		"job_timeout":        c.JobTimeout,
+		"retry_backoff_base": c.RetryBackoffBase,
+		"retry_backoff_cap":  c.RetryBackoffCap,
+	} {
+		if d.Duration%time.Second != 0 {
+			errs = append(errs, fmt.Errorf("%s must be a whole number of seconds, got %s", name, d))
+		}
+	}
+	if c.MaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("max_retries must not be negative, got %d", c.MaxRetries))
+	}
+	if c.ModelChunkSize <= 0 {
+		errs = append(errs, fmt.Errorf("model_chunk_size must be positive, got %d", c.ModelChunkSize))
+	}
+	if c.GRPCPort < 0 || c.GRPCPort > 65535 {
+		errs = append(errs, fmt.Errorf("grpc_port must be between 0 and 65535, got %d", c.GRPCPort))
+	}
+	// A healthy job must be able to miss one heartbeat without being declared stuck
+	if c.JobTimeout.Duration < 2*c.HeartbeatInterval.Duration {
+		errs = append(errs, fmt.Errorf("job_timeout (%s) must be at least twice heartbeat_interval (%s)", c.JobTimeout, c.HeartbeatInterval))
+	}
+	if c.RetryBackoffCap.Duration < c.RetryBackoffBase.Duration {
+		errs = append(errs, fmt.Errorf("retry_backoff_cap (%s) must be at least retry_backoff_base (%s)", c.RetryBackoffCap, c.RetryBackoffBase))
+	}
+	// hashicorp/raft refuses to start otherwise
+	if c.Raft.LeaderLeaseTimeout.Duration > c.Raft.HeartbeatTimeout.Duration {
+		errs = append(errs, fmt.Errorf("raft.leader_lease_timeout (%s) must not exceed raft.heartbeat_timeout (%s)", c.Raft.LeaderLeaseTimeout, c.Raft.HeartbeatTimeout))
+	}
+	if c.Raft.ElectionTimeout.Duration < c.Raft.HeartbeatTimeout.Duration {
+		errs = append(errs, fmt.Errorf("raft.election_timeout (%s) must be at least raft.heartbeat_timeout (%s)", c.Raft.ElectionTimeout, c.Raft.HeartbeatTimeout))
+	}
+	return errors.Join(errs...)
+}
+
+// Load builds the effective config: defaults, then the file at path (if any),
+// then the environment, then flags. The result is validated.
+func Load(path string, flags *Flags) (Config, error) {
+	c := Default()
+	if path != "" {
+		if err := c.LoadFile(path); err != nil {
+			return c, err
+		}
+	}
+	if err := c.ApplyEnv(os.LookupEnv); err != nil {
+		return c, err
+	}
+	if flags != nil {
+		if err := flags.Apply(&c); err != nil {
+			return c, err
+		}
+	}
+	return c, c.Validate()
+}
+
+// setting maps a flag/env name onto a Config field
+type setting struct {
+	name  string
+	usage string
+	set   func(c *Config, v string) error
+}
+
+var settings = []setting{
+	{"data-dir", "Directory for Raft data", func(c *Config, v string) error { c.DataDir = v; return nil }},
+	{"job-timeout", "Time without a heartbeat before a running job is stuck", durationSetter(func(c *Config) *Duration { return &c.JobTimeout })},
+	{"max-retries", "Default retries before a job is marked FAILED", intSetter(func(c *Config) *int { return &c.MaxRetries })},
+	{"health-check-interval", "How often the leader looks for stuck jobs", durationSetter(func(c *Config) *Duration { return &c.HealthCheckInterval })},
+	{"heartbeat-interval", "How often workers heartbeat running jobs", durationSetter(func(c *Config) *Duration { return &c.HeartbeatInterval })},
+	{"retry-backoff-base", "Default delay before the first retry", durationSetter(func(c *Config) *Duration { return &c.RetryBackoffBase })},
+	{"retry-backoff-cap", "Default upper bound on the retry delay", durationSetter(func(c *Config) *Duration { return &c.RetryBackoffCap })},
+	{"aggregator-poll-interval", "How often the aggregator looks for finished parents", durationSetter(func(c *Config) *Duration { return &c.AggregatorPollInterval })},
+	{"grpc-port", "Port for the model/gradient gRPC service (0 disables it)", intSetter(func(c *Config) *int { return &c.GRPCPort })},
+	{"model-chunk-size", "Bytes per streamed model chunk", intSetter(func(c *Config) *int { return &c.ModelChunkSize })},
+	{"raft-heartbeat-timeout", "Raft heartbeat timeout", durationSetter(func(c *Config) *Duration { return &c.Raft.HeartbeatTimeout })},
+	{"raft-election-timeout", "Raft election timeout", durationSetter(func(c *Config) *Duration { return &c.Raft.ElectionTimeout })},
+	{"raft-commit-timeout", "Raft commit timeout", durationSetter(func(c *Config) *Duration { return &c.Raft.CommitTimeout })},
+	{"raft-leader-lease-timeout", "Raft leader lease timeout", durationSetter(func(c *Config) *Duration { return &c.Raft.LeaderLeaseTimeout })},
+}
+
+func durationSetter(field func(*Config) *Duration) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		field(c).Duration = d
+		return nil
+	}
+}
+
+func intSetter(field func(*Config) *int) func(*Config, string) error {
+	return func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return err
+		}
+		*field(c) = n
+		return nil
+	}
+}
+
+// Duration is a time.Duration written as a string ("15s", "500ms") in JSON
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"15s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
@@ -2,6 +2,7 @@ package consensus
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 
@@ -15,15 +16,95 @@ type CommandType string
 const (
 	CmdSetJob          CommandType = "SET_JOB"
 	CmdSubmitParentJob CommandType = "SUBMIT_PARENT_JOB"
+	CmdSetNode         CommandType = "SET_NODE"
+	CmdRemoveNode      CommandType = "REMOVE_NODE"
+	CmdCancelJob       CommandType = "CANCEL_JOB"
+	CmdHeartbeat       CommandType = "HEARTBEAT"
+	CmdClaimJob        CommandType = "CLAIM_JOB"
+	CmdUpdateJob       CommandType = "UPDATE_JOB"
+	CmdFailJob         CommandType = "FAIL_JOB"
+	CmdRequeueJob      CommandType = "REQUEUE_JOB"
+	CmdAggregate       CommandType = "RECORD_AGGREGATION"
+	CmdAdvanceRound    CommandType = "ADVANCE_ROUND"
 )
 
+var (
+	// ErrJobNotFound is returned by Apply when a command names a job that does not exist
+	ErrJobNotFound = errors.New("job not found")
+	// ErrNotAssigned is returned when a worker reports on a job it is no longer running
+	ErrNotAssigned = errors.New("job is not running on this worker")
+	// ErrClaimConflict is returned when a claim's expected status/worker/epoch no longer hold
+	ErrClaimConflict = errors.New("job claim precondition failed")
+	// ErrPreconditionFailed is returned when an UPDATE_JOB's precondition does not hold
+	ErrPreconditionFailed = errors.New("job update precondition failed")
+	// ErrAlreadyAggregated is returned when a parent's aggregation was recorded before
+	ErrAlreadyAggregated = errors.New("parent job already aggregated")
+	// ErrRoundAdvanced is returned when an ADVANCE_ROUND names a round that is no longer current
+	ErrRoundAdvanced = errors.New("training round already advanced")
+	// ErrJobExists is returned when a submission reuses the ID of a job, parent job or training run
+	ErrJobExists = errors.New("job already exists")
+)
+
+// Precondition guards an UPDATE_JOB. Every non-zero field must match the
+// current job, otherwise the update is rejected and nothing changes.
+type Precondition struct {
+	Status   store.JobStatus `json:"expected_status,omitempty"`
+	WorkerID string          `json:"expected_worker_id,omitempty"`
+	Revision uint64          `json:"expected_revision,omitempty"`
+	// ModifyIndex is the Raft index of the job's last change as the caller saw it
+	ModifyIndex uint64 `json:"expected_modify_index,omitempty"`
+}
+
+// check reports why job does not satisfy p, or nil if it does
+func (p *Precondition) check(job *store.Job) error {
+	if p == nil {
+		return nil
+	}
+	if (p.Status != "" && job.Status != p.Status) ||
+		(p.WorkerID != "" && job.WorkerID != p.WorkerID) ||
+		(p.Revision != 0 && job.Revision != p.Revision) ||
+		(p.ModifyIndex != 0 && job.ModifyIndex != p.ModifyIndex) {
+		return fmt.Errorf("%w: %s is %s on %s at revision %d (index %d), expected %+v",
+			ErrPreconditionFailed, job.ID, job.Status, job.WorkerID, job.Revision, job.ModifyIndex, *p)
+	}
+	return nil
+}
+
+// UpdateResult is the response Apply returns for UPDATE_JOB
+type UpdateResult struct {
+	Job *store.Job // The job after the update, or as it is now if the update was rejected
+	Err error      // nil, ErrJobNotFound or ErrPreconditionFailed (wrapped)
+}
+
 // LogEvent is what we actually write to the Raft log
 type LogEvent struct {
 	Type        CommandType `json:"type"`
 	JobID       string      `json:"job_id"`
-	Job         *store.Job  `json:"job,omitempty"`  // Job data for SET_JOB
-	Data        *store.Job  `json:"data,omitempty"` // Deprecated: use Job instead
-	ClusterSize int         `json:"cluster_size,omitempty"` // For parent job splitting
+	Job         *store.Job  `json:"job,omitempty"`          // Job data for SET_JOB
+	Data        *store.Job  `json:"data,omitempty"`         // Deprecated: use Job instead
+	ClusterSize int         `json:"cluster_size,omitempty"` // Deprecated: use Workers instead
+	Workers     []string    `json:"workers,omitempty"`      // Worker set recorded at submit time, one shard each
+	Node        *store.Node `json:"node,omitempty"`         // Node data for SET_NODE / REMOVE_NODE
+	Timestamp   int64       `json:"timestamp,omitempty"`    // Unix time chosen by the proposer, so Apply stays deterministic
+	WorkerID    string      `json:"worker_id,omitempty"`    // Reporting worker for HEARTBEAT / CLAIM_JOB
+	RetryEpoch  int         `json:"retry_epoch,omitempty"`  // RetryCount the claimant saw, for CLAIM_JOB
+
+	Precondition *Precondition `json:"precondition,omitempty"` // Must hold for UPDATE_JOB to apply
+
+	// Recorded in the job's history: Cause is an extra event that led to the
+	// change (e.g. HEARTBEAT_LOST), Reason explains a reassignment or failure
+	Cause  store.JobEventType `json:"cause,omitempty"`
+	Reason string             `json:"reason,omitempty"`
+
+	// For FAIL_JOB: what went wrong, the policy to use if the job has none,
+	// and where a retry runs (empty keeps the current worker).
+	// REQUEUE_JOB uses NextWorker the same way.
+	Failure     *store.Failure     `json:"failure,omitempty"`
+	RetryPolicy *store.RetryPolicy `json:"retry_policy,omitempty"`
+	NextWorker  string             `json:"next_worker,omitempty"`
+
+	Aggregation *store.Aggregation `json:"aggregation,omitempty"` // Merge outcome for RECORD_AGGREGATION
+	Round       *store.Round       `json:"round,omitempty"`       // Finished round for ADVANCE_ROUND; Workers run the next one
 }
 
 // FSM implementation
@@ -41,6 +122,8 @@ func (f *FSM) Apply(l *raft.Log) interface{} {
 	if err := json.Unmarshal(l.Data, &event); err != nil {
 		panic(fmt.Sprintf("failed to unmarshal command: %s", err.Error()))
 	}
+	// Every job written by this entry is stamped with its position in the log
+	pos := store.LogPosition{Index: l.Index, Term: l.Term}
 
 	switch event.Type {
 	case CmdSetJob:
@@ -49,33 +132,458 @@ func (f *FSM) Apply(l *raft.Log) interface{} {
 		if job == nil {
 			job = event.Data
 		}
-		f.state.Apply(event.JobID, job)
+		if job == nil {
+			return fmt.Errorf("invalid job update: missing job data")
+		}
+		jobID := event.JobID
+		if jobID == "" {
+			jobID = job.ID
+		}
+		f.putJob(pos, event, jobID, job)
 		return nil
 	case CmdSubmitParentJob:
-		// Split parent job into sub-jobs for each node
-		parentJob := event.Job
-		if parentJob == nil {
-			parentJob = event.Data
+		return f.applySubmit(pos, event)
+	case CmdSetNode:
+		if event.Node == nil || event.Node.ID == "" {
+			return fmt.Errorf("invalid node registration: missing node data")
 		}
-		if parentJob == nil || event.ClusterSize == 0 {
-			return fmt.Errorf("invalid parent job: missing data or cluster size")
+		f.state.SetNode(event.Node)
+		return nil
+	case CmdRemoveNode:
+		if event.Node == nil || event.Node.ID == "" {
+			return fmt.Errorf("invalid node removal: missing node ID")
 		}
+		f.state.RemoveNode(event.Node.ID)
+		return nil
+	case CmdCancelJob:
+		return f.applyCancel(pos, event)
+	case CmdHeartbeat:
+		return f.applyHeartbeat(pos, event)
+	case CmdClaimJob:
+		return f.applyClaim(pos, event)
+	case CmdUpdateJob:
+		return f.applyUpdate(pos, event)
+	case CmdFailJob:
+		return f.applyFail(pos, event)
+	case CmdRequeueJob:
+		return f.applyRequeue(pos, event)
+	case CmdAggregate:
+		return f.applyAggregation(pos, event)
+	case CmdAdvanceRound:
+		return f.applyAdvanceRound(pos, event)
+	default:
+		return fmt.Errorf("unknown command type: %s", event.Type)
+	}
+}
+
+// applySubmit splits a parent job into one sub-job per recorded worker. A job
+// asking for multi-round training becomes a TrainingRun whose rounds are
+// such parents, starting with the first.
+func (f *FSM) applySubmit(pos store.LogPosition, event LogEvent) interface{} {
+	parentJob := event.Job
+	if parentJob == nil {
+		parentJob = event.Data
+	}
+	workers := event.Workers
+	if len(workers) == 0 {
+		// Entries written before the worker set was recorded assume node-1..node-N
 		for i := 1; i <= event.ClusterSize; i++ {
-			nodeID := NodeIDFromIndex(i)
-			subJobID := fmt.Sprintf("%s-%s", event.JobID, nodeID)
-			subJob := &store.Job{
-				ID:        subJobID,
-				Type:      parentJob.Type,
-				Status:    store.StatusPending,
-				WorkerID:  nodeID,
-				ResultURL: "",
-			}
-			f.state.Apply(subJobID, subJob)
+			workers = append(workers, NodeIDFromIndex(i))
+		}
+	}
+	if parentJob == nil || len(workers) == 0 {
+		return fmt.Errorf("invalid parent job: missing data or workers")
+	}
+	// Resubmitting would replace the record and revive a finished parent
+	// with the old aggregation, so every ID the submission creates must be new
+	parentID := event.JobID
+	if parentJob.Training != nil {
+		parentID = store.RoundParentID(event.JobID, 1)
+	}
+	if id := f.parentIDInUse(parentID, workers, event.JobID); id != "" {
+		return fmt.Errorf("%w: %s", ErrJobExists, id)
+	}
+
+	if parentJob.Training == nil {
+		f.submitParent(pos, event, &store.ParentJob{
+			ID:          event.JobID,
+			Type:        parentJob.Type,
+			SubmittedAt: event.Timestamp,
+			RetryPolicy: parentJob.RetryPolicy,
+			Aggregator:  parentJob.Aggregator,
+		}, workers, "")
+		return nil
+	}
+	if err := parentJob.Training.Validate(); err != nil {
+		return fmt.Errorf("invalid training run %s: %w", event.JobID, err)
+	}
+	run := &store.TrainingRun{
+		ID:          event.JobID,
+		Type:        parentJob.Type,
+		Status:      store.TrainingRunning,
+		Config:      *parentJob.Training,
+		RetryPolicy: parentJob.RetryPolicy.Clone(),
+		Aggregator:  parentJob.Aggregator.Clone(),
+		SubmittedAt: event.Timestamp,
+	}
+	f.startRound(pos, event, run, workers, "")
+	return nil
+}
+
+// parentIDInUse returns the first of the IDs a parent job split across
+// workers would create (the parent's and its sub-jobs'), plus extra, that is
+// already in use, or "" if all of them are free
+func (f *FSM) parentIDInUse(parentID string, workers []string, extra ...string) string {
+	ids := append(extra, parentID)
+	for _, nodeID := range workers {
+		ids = append(ids, SubJobID(parentID, nodeID))
+	}
+	for _, id := range ids {
+		if f.idInUse(id) {
+			return id
+		}
+	}
+	return ""
+}
+
+// idInUse reports whether id names an existing job, parent job or training run
+func (f *FSM) idInUse(id string) bool {
+	if _, ok := f.state.GetJob(id); ok {
+		return true
+	}
+	if _, ok := f.state.GetParent(id); ok {
+		return true
+	}
+	_, ok := f.state.GetTraining(id)
+	return ok
+}
+
+// submitParent records parent and creates one PENDING sub-job per worker,
+// each training its shard from initialModel (if any)
+func (f *FSM) submitParent(pos store.LogPosition, event LogEvent, parent *store.ParentJob, workers []string, initialModel string) {
+	for _, nodeID := range workers {
+		parent.ChildIDs = append(parent.ChildIDs, SubJobID(parent.ID, nodeID))
+	}
+	f.state.CreateParentAt(pos, parent)
+
+	for i, nodeID := range workers {
+		subJobID := SubJobID(parent.ID, nodeID)
+		subJob := &store.Job{
+			ID:           subJobID,
+			Type:         parent.Type,
+			Status:       store.StatusPending,
+			WorkerID:     nodeID,
+			ResultURL:    "",
+			ParentID:     parent.ID,
+			ShardIndex:   i,
+			TotalShards:  len(workers),
+			RetryPolicy:  parent.RetryPolicy.Clone(),
+			InitialModel: initialModel,
 		}
+		f.putJob(pos, event, subJobID, subJob)
+	}
+}
+
+// startRound records the next round of run and submits its parent job over
+// workers, starting from initialModel
+func (f *FSM) startRound(pos store.LogPosition, event LogEvent, run *store.TrainingRun, workers []string, initialModel string) {
+	n := len(run.Rounds) + 1
+	parentID := store.RoundParentID(run.ID, n)
+	run.Rounds = append(run.Rounds, store.Round{
+		Round:        n,
+		ParentID:     parentID,
+		InitialModel: initialModel,
+		Status:       store.ParentRunning,
+		StartedAt:    event.Timestamp,
+	})
+	f.state.SetTrainingAt(pos, run)
+	f.submitParent(pos, event, &store.ParentJob{
+		ID:          parentID,
+		Type:        run.Type,
+		SubmittedAt: event.Timestamp,
+		RetryPolicy: run.RetryPolicy,
+		Aggregator:  run.Aggregator,
+		TrainingID:  run.ID,
+		Round:       n,
+	}, workers, initialModel)
+}
+
+// applyCancel cancels a job, or every unfinished sub-job when JobID names a
+// parent. Cancelling a running training run ends it and cancels its current round.
+func (f *FSM) applyCancel(pos store.LogPosition, event LogEvent) interface{} {
+	targets := f.state.GetSubJobs(event.JobID)
+	if job, ok := f.state.GetJob(event.JobID); ok {
+		targets = append(targets, job)
+	}
+	run, isRun := f.state.GetTraining(event.JobID)
+	if isRun && run.Status == store.TrainingRunning {
+		current := run.CurrentRound()
+		current.Status = store.ParentCancelled
+		current.FinishedAt = event.Timestamp
+		run.Status = store.TrainingCancelled
+		run.FinishedAt = event.Timestamp
+		f.state.SetTrainingAt(pos, run)
+		targets = append(targets, f.state.GetSubJobs(current.ParentID)...)
+	}
+	if len(targets) == 0 && !isRun {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, event.JobID)
+	}
+
+	for _, job := range targets {
+		if job.Status.IsTerminal() {
+			continue
+		}
+		cancelled := *job
+		cancelled.Status = store.StatusCancelled
+		cancelled.UpdatedAt = event.Timestamp
+		f.putJob(pos, event, cancelled.ID, &cancelled)
+	}
+	return nil
+}
+
+// applyHeartbeat records that the assigned worker is still running the job.
+// Heartbeats from a worker the job was taken away from are rejected.
+func (f *FSM) applyHeartbeat(pos store.LogPosition, event LogEvent) interface{} {
+	job, ok := f.state.GetJob(event.JobID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, event.JobID)
+	}
+	if job.Status != store.StatusRunning || job.WorkerID != event.WorkerID {
+		return fmt.Errorf("%w: %s is %s on %s", ErrNotAssigned, job.ID, job.Status, job.WorkerID)
+	}
+
+	updated := *job
+	updated.LastHeartbeat = event.Timestamp
+	f.putJob(pos, event, updated.ID, &updated)
+	return nil
+}
+
+// applyClaim atomically moves a PENDING job to RUNNING for the claiming worker.
+// The claim only wins if the job is still PENDING, still assigned to that worker,
+// and has not been reassigned since the worker looked at it (same retry epoch),
+// so two workers can never both start the same job. Claiming is idempotent:
+// a claim the job already records (RUNNING on that worker at that epoch)
+// succeeds without touching the job, so a worker whose claim committed but
+// whose response was lost still runs the job when it retries.
+func (f *FSM) applyClaim(pos store.LogPosition, event LogEvent) interface{} {
+	job, ok := f.state.GetJob(event.JobID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, event.JobID)
+	}
+	if job.Status == store.StatusRunning && job.WorkerID == event.WorkerID && job.RetryCount == event.RetryEpoch {
 		return nil
+	}
+	if event.Timestamp < job.NotBefore {
+		return fmt.Errorf("%w: %s is backing off until %d", ErrClaimConflict, job.ID, job.NotBefore)
+	}
+	if job.Status != store.StatusPending || job.WorkerID != event.WorkerID || job.RetryCount != event.RetryEpoch {
+		return fmt.Errorf("%w: %s is %s on %s (epoch %d), claim by %s expected epoch %d",
+			ErrClaimConflict, job.ID, job.Status, job.WorkerID, job.RetryCount, event.WorkerID, event.RetryEpoch)
+	}
+
+	claimed := *job
+	claimed.Status = store.StatusRunning
+	claimed.StartedAt = event.Timestamp
+	claimed.UpdatedAt = event.Timestamp
+	claimed.LastHeartbeat = 0
+	claimed.Failure = store.Failure{}
+	f.putJob(pos, event, claimed.ID, &claimed)
+	return nil
+}
+
+// applyUpdate replaces an existing, unfinished job, but only if the event's
+// precondition holds. Unlike SET_JOB it never creates a job or changes a
+// COMPLETED, FAILED or CANCELLED one (REQUEUE_JOB revives failed jobs), and
+// it always answers with an *UpdateResult so the proposer learns the job's
+// current state either way.
+func (f *FSM) applyUpdate(pos store.LogPosition, event LogEvent) interface{} {
+	if event.Job == nil {
+		return &UpdateResult{Err: fmt.Errorf("invalid job update: missing job data")}
+	}
+	jobID := event.JobID
+	if jobID == "" {
+		jobID = event.Job.ID
+	}
+	current, ok := f.state.GetJob(jobID)
+	if !ok {
+		return &UpdateResult{Err: fmt.Errorf("%w: %s", ErrJobNotFound, jobID)}
+	}
+	// Finished jobs stay finished, e.g. a report that raced a cancel must not revive the job
+	if current.Status.IsTerminal() {
+		return &UpdateResult{Job: current, Err: fmt.Errorf("%w: %s is already %s", ErrPreconditionFailed, current.ID, current.Status)}
+	}
+	if err := event.Precondition.check(current); err != nil {
+		return &UpdateResult{Job: current, Err: err}
+	}
+
+	f.putJob(pos, event, jobID, event.Job)
+	updated, _ := f.state.GetJob(jobID)
+	return &UpdateResult{Job: updated}
+}
+
+// applyFail records a failed attempt and applies the job's retry policy (or
+// the one recorded in the entry): while attempts remain and the failure is
+// retryable, the job goes back to PENDING on event.NextWorker and may not be
+// claimed before the backoff has passed; otherwise it ends FAILED.
+// Like UPDATE_JOB, it answers with an *UpdateResult.
+func (f *FSM) applyFail(pos store.LogPosition, event LogEvent) interface{} {
+	if event.Failure == nil {
+		return &UpdateResult{Err: fmt.Errorf("invalid job failure: missing failure data")}
+	}
+	job, ok := f.state.GetJob(event.JobID)
+	if !ok {
+		return &UpdateResult{Err: fmt.Errorf("%w: %s", ErrJobNotFound, event.JobID)}
+	}
+	if job.Status.IsTerminal() {
+		return &UpdateResult{Job: job, Err: fmt.Errorf("%w: %s is already %s", ErrPreconditionFailed, job.ID, job.Status)}
+	}
+	if err := event.Precondition.check(job); err != nil {
+		return &UpdateResult{Job: job, Err: err}
+	}
+
+	policy := job.RetryPolicy
+	if policy == nil {
+		policy = event.RetryPolicy
+	}
+	if event.Reason == "" {
+		event.Reason = event.Failure.Error
+	}
+
+	failed := *job
+	failed.Failure = *event.Failure
+	failed.UpdatedAt = event.Timestamp
+	// Attempts are counted from the last dead-letter requeue, if any
+	retries := job.RetryCount - job.RequeuedAtRetry
+	if policy != nil && policy.Retryable(event.Failure.FailureCategory) && retries+1 < policy.MaxAttempts {
+		failed.Status = store.StatusPending
+		failed.RetryCount++
+		failed.NotBefore = event.Timestamp + policy.Backoff(retries+1)
+		failed.StartedAt = 0
+		failed.LastHeartbeat = 0
+		if event.NextWorker != "" {
+			failed.WorkerID = event.NextWorker
+		}
+		// The history shows the failed attempt before the requeue
+		if event.Cause == "" {
+			event.Cause = store.EventFailed
+		}
+	} else {
+		failed.Status = store.StatusFailed
+	}
+
+	f.putJob(pos, event, failed.ID, &failed)
+	updated, _ := f.state.GetJob(failed.ID)
+	return &UpdateResult{Job: updated}
+}
+
+// applyRequeue takes a job out of the dead letter: a FAILED job goes back to
+// PENDING on event.NextWorker (or its current worker) with a fresh set of
+// attempts under its retry policy. RetryCount still increases, so claims made
+// for an earlier attempt cannot win. Like UPDATE_JOB, it answers with an *UpdateResult.
+func (f *FSM) applyRequeue(pos store.LogPosition, event LogEvent) interface{} {
+	job, ok := f.state.GetJob(event.JobID)
+	if !ok {
+		return &UpdateResult{Err: fmt.Errorf("%w: %s", ErrJobNotFound, event.JobID)}
+	}
+	if job.Status != store.StatusFailed {
+		return &UpdateResult{Job: job, Err: fmt.Errorf("%w: %s is %s, only FAILED jobs can be requeued", ErrPreconditionFailed, job.ID, job.Status)}
+	}
+	if err := event.Precondition.check(job); err != nil {
+		return &UpdateResult{Job: job, Err: err}
+	}
+
+	requeued := *job
+	requeued.Status = store.StatusPending
+	requeued.RetryCount++
+	requeued.RequeuedAtRetry = requeued.RetryCount
+	requeued.Requeues++
+	requeued.NotBefore = 0
+	requeued.StartedAt = 0
+	requeued.LastHeartbeat = 0
+	requeued.UpdatedAt = event.Timestamp
+	if event.NextWorker != "" {
+		requeued.WorkerID = event.NextWorker
+	}
+
+	f.putJob(pos, event, requeued.ID, &requeued)
+	updated, _ := f.state.GetJob(requeued.ID)
+	return &UpdateResult{Job: updated}
+}
+
+// applyAggregation records the merge of a parent's models. Only the first
+// record for a parent is kept, so a leader that merged a parent its
+// predecessor had already recorded cannot overwrite the result.
+func (f *FSM) applyAggregation(pos store.LogPosition, event LogEvent) interface{} {
+	a := event.Aggregation
+	if a == nil || a.ParentID == "" {
+		return fmt.Errorf("invalid aggregation: missing parent")
+	}
+	if len(f.state.GetSubJobs(a.ParentID)) == 0 {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, a.ParentID)
+	}
+	if prev, ok := f.state.GetAggregation(a.ParentID); ok {
+		return fmt.Errorf("%w: %s was %s at index %d", ErrAlreadyAggregated, a.ParentID, prev.Status, prev.Index)
+	}
+
+	recorded := *a
+	recorded.Time = event.Timestamp
+	recorded.Index, recorded.Term = pos.Index, pos.Term
+	f.state.SetAggregation(&recorded)
+	return nil
+}
+
+// applyAdvanceRound records how the current round of a training run ended,
+// then either starts the next round over event.Workers from the round's
+// global model, or ends the run: when the round did not merge, when it moved
+// the global model less than the convergence threshold, or after the last
+// round. Only the current round can be advanced, so the first proposal wins.
+func (f *FSM) applyAdvanceRound(pos store.LogPosition, event LogEvent) interface{} {
+	finished := event.Round
+	if finished == nil {
+		return fmt.Errorf("invalid round: missing round data")
+	}
+	run, ok := f.state.GetTraining(event.JobID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, event.JobID)
+	}
+	current := run.CurrentRound()
+	if run.Status != store.TrainingRunning || current == nil || current.Round != finished.Round {
+		return fmt.Errorf("%w: %s is %s in round %d, not %d", ErrRoundAdvanced, run.ID, run.Status, len(run.Rounds), finished.Round)
+	}
+
+	recorded := *finished
+	recorded.ParentID, recorded.InitialModel, recorded.StartedAt = current.ParentID, current.InitialModel, current.StartedAt
+	recorded.FinishedAt = event.Timestamp
+	*current = recorded
+	if recorded.Status == store.ParentMerged {
+		run.GlobalModelPath = recorded.ModelPath
+	}
+
+	switch {
+	case recorded.Status == store.ParentCancelled:
+		run.Status = store.TrainingCancelled
+	case recorded.Status != store.ParentMerged:
+		run.Status = store.TrainingFailed
+	case recorded.InitialModel != "" && recorded.UpdateNorm < run.Config.ConvergenceThreshold:
+		run.Status = store.TrainingConverged
+	case recorded.Round >= run.Config.Rounds:
+		run.Status = store.TrainingCompleted
+	case len(event.Workers) == 0:
+		return fmt.Errorf("invalid round: no workers for round %d of %s", recorded.Round+1, run.ID)
 	default:
-		return fmt.Errorf("unknown command type: %s", event.Type)
+		// Reusing a job submitted under the next round's ID would inherit
+		// its state, e.g. a recorded aggregation that ends the round untrained
+		id := f.parentIDInUse(store.RoundParentID(run.ID, recorded.Round+1), event.Workers)
+		if id == "" {
+			f.startRound(pos, event, run, event.Workers, recorded.ModelPath)
+			return nil
+		}
+		run.Status = store.TrainingFailed
+		run.Error = fmt.Sprintf("cannot start round %d: %s already exists", recorded.Round+1, id)
 	}
+	run.FinishedAt = event.Timestamp
+	f.state.SetTrainingAt(pos, run)
+	return nil
 }
 
 // Snapshot returns a point-in-time snapshot of the system
@@ -125,6 +633,11 @@ func NodeIDFromIndex(index int) string {
 	return fmt.Sprintf("node-%d", index)
 }
 
+// SubJobID names the sub-job of parentID that is initially assigned to nodeID
+func SubJobID(parentID, nodeID string) string {
+	return fmt.Sprintf("%s-%s", parentID, nodeID)
+}
+
 // MustMarshalEvent marshals a LogEvent and panics on error (for internal use)
 func MustMarshalEvent(event LogEvent) []byte {
 	data, err := json.Marshal(event)
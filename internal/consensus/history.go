@@ -0,0 +1,74 @@
+package consensus
+
+import "github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+
+// putJob stores job as written by the entry at pos and appends the history
+// events its transition implies, so every write path keeps the history current.
+func (f *FSM) putJob(pos store.LogPosition, event LogEvent, jobID string, job *store.Job) {
+	prev, existed := f.state.GetJob(jobID)
+	if !existed {
+		prev = nil
+	}
+	f.state.ApplyAt(pos, jobID, job)
+	f.state.AppendJobEvents(jobID, transitionEvents(pos, event, prev, job)...)
+}
+
+// transitionEvents describes the change from prev (nil for a new job) to next.
+// The entry's Cause, if any, comes first; its Reason explains reassignments and failures.
+func transitionEvents(pos store.LogPosition, event LogEvent, prev, next *store.Job) []store.JobEvent {
+	at := event.Timestamp
+	if at == 0 {
+		at = next.UpdatedAt
+	}
+	newEvent := func(typ store.JobEventType) store.JobEvent {
+		return store.JobEvent{Type: typ, Time: at, Index: pos.Index, Term: pos.Term, WorkerID: next.WorkerID}
+	}
+
+	var events []store.JobEvent
+	if event.Cause != "" {
+		e := newEvent(event.Cause)
+		e.Reason = event.Reason
+		if prev != nil {
+			e.WorkerID = prev.WorkerID
+		}
+		events = append(events, e)
+	}
+	if prev == nil {
+		return append(events, newEvent(store.EventCreated))
+	}
+
+	reassigned := prev.WorkerID != next.WorkerID
+	if reassigned {
+		e := newEvent(store.EventReassigned)
+		e.FromWorker = prev.WorkerID
+		e.Reason = event.Reason
+		events = append(events, e)
+	}
+	if prev.Status == next.Status {
+		return events
+	}
+
+	switch next.Status {
+	case store.StatusRunning:
+		if event.Type == CmdClaimJob {
+			events = append(events, newEvent(store.EventClaimed))
+		} else {
+			events = append(events, newEvent(store.EventRunning))
+		}
+	case store.StatusPending:
+		if !reassigned {
+			e := newEvent(store.EventRequeued)
+			e.Reason = event.Reason
+			events = append(events, e)
+		}
+	case store.StatusFailed:
+		e := newEvent(store.EventFailed)
+		e.Reason = event.Reason
+		events = append(events, e)
+	case store.StatusCompleted:
+		events = append(events, newEvent(store.EventCompleted))
+	case store.StatusCancelled:
+		events = append(events, newEvent(store.EventCancelled))
+	}
+	return events
+}
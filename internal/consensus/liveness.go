@@ -0,0 +1,77 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// peerLiveness tracks which peers the leader currently fails to heartbeat.
+// hashicorp/raft reports every failed heartbeat to a peer and, once the peer
+// answers again, that it resumed. Only the leader sends heartbeats, so the
+// view starts afresh whenever leadership changes.
+type peerLiveness struct {
+	mu      sync.Mutex
+	failing map[raft.ServerID]time.Time // Peer -> when it last answered (zero if never)
+
+	ch       chan raft.Observation
+	observer *raft.Observer
+	stopped  sync.Once
+}
+
+func watchPeerLiveness(r *raft.Raft) *peerLiveness {
+	l := &peerLiveness{
+		failing: make(map[raft.ServerID]time.Time),
+		ch:      make(chan raft.Observation, 64),
+	}
+	l.observer = raft.NewObserver(l.ch, false, func(o *raft.Observation) bool {
+		switch o.Data.(type) {
+		case raft.FailedHeartbeatObservation, raft.ResumedHeartbeatObservation, raft.LeaderObservation:
+			return true
+		}
+		return false
+	})
+	r.RegisterObserver(l.observer)
+	go l.run()
+	return l
+}
+
+func (l *peerLiveness) run() {
+	for o := range l.ch {
+		l.mu.Lock()
+		switch data := o.Data.(type) {
+		case raft.FailedHeartbeatObservation:
+			l.failing[data.PeerID] = data.LastContact
+		case raft.ResumedHeartbeatObservation:
+			delete(l.failing, data.PeerID)
+		case raft.LeaderObservation:
+			clear(l.failing)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// stop ends the tracking; calling it again does nothing
+func (l *peerLiveness) stop(r *raft.Raft) {
+	l.stopped.Do(func() {
+		r.DeregisterObserver(l.observer)
+		close(l.ch)
+	})
+}
+
+// Unreachable returns the peers this node, as leader, currently fails to
+// heartbeat, with the last time each of them answered (zero if it never did).
+// Followers do not heartbeat anyone and get an empty map.
+func (n *RaftNode) Unreachable() map[string]time.Time {
+	unreachable := make(map[string]time.Time)
+	if n.liveness == nil || !n.IsLeader() {
+		return unreachable
+	}
+	n.liveness.mu.Lock()
+	defer n.liveness.mu.Unlock()
+	for id, lastContact := range n.liveness.failing {
+		unreachable[string(id)] = lastContact
+	}
+	return unreachable
+}
@@ -1,6 +1,8 @@
 package consensus
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
@@ -16,12 +18,38 @@ type RaftNode struct {
 	FSM         *FSM
 	logStore    raft.LogStore    // Keep reference to close on shutdown
 	stableStore raft.StableStore // Keep reference to close on shutdown
+	liveness    *peerLiveness    // Peers the leader fails to heartbeat
+}
+
+// Timing overrides hashicorp/raft timeouts. Zero values keep raft's defaults.
+type Timing struct {
+	HeartbeatTimeout   time.Duration
+	ElectionTimeout    time.Duration
+	CommitTimeout      time.Duration
+	LeaderLeaseTimeout time.Duration
 }
 
 func NewRaftNode(nodeID, raftAddr, raftDir string, state *store.State) (*RaftNode, error) {
+	return NewRaftNodeWithTiming(nodeID, raftAddr, raftDir, state, Timing{})
+}
+
+// NewRaftNodeWithTiming is NewRaftNode with tuned Raft timeouts
+func NewRaftNodeWithTiming(nodeID, raftAddr, raftDir string, state *store.State, timing Timing) (*RaftNode, error) {
 
 	config := raft.DefaultConfig()
 	config.LocalID = raft.ServerID(nodeID)
+	if timing.HeartbeatTimeout > 0 {
+		config.HeartbeatTimeout = timing.HeartbeatTimeout
+	}
+	if timing.ElectionTimeout > 0 {
+		config.ElectionTimeout = timing.ElectionTimeout
+	}
+	if timing.CommitTimeout > 0 {
+		config.CommitTimeout = timing.CommitTimeout
+	}
+	if timing.LeaderLeaseTimeout > 0 {
+		config.LeaderLeaseTimeout = timing.LeaderLeaseTimeout
+	}
 
 	// Setup TCP Transport
 	addr, err := net.ResolveTCPAddr("tcp", raftAddr)
@@ -59,7 +87,168 @@ func NewRaftNode(nodeID, raftAddr, raftDir string, state *store.State) (*RaftNod
 		return nil, err
 	}
 
-	return &RaftNode{Raft: r, FSM: fsm, logStore: logStore, stableStore: stableStore}, nil
+	return &RaftNode{Raft: r, FSM: fsm, logStore: logStore, stableStore: stableStore, liveness: watchPeerLiveness(r)}, nil
+}
+
+// ErrNoLeader is returned when the cluster currently has no known leader
+var ErrNoLeader = errors.New("no known leader")
+
+// IsLeader reports whether this node is currently the Raft leader
+func (n *RaftNode) IsLeader() bool {
+	return n.Raft.State() == raft.Leader
+}
+
+// LeaderHTTPAddr returns the HTTP address of the current leader as recorded in the node registry
+func (n *RaftNode) LeaderHTTPAddr() (string, error) {
+	leaderAddr, _ := n.Raft.LeaderWithID()
+	if leaderAddr == "" {
+		return "", ErrNoLeader
+	}
+	node, ok := n.FSM.state.GetNodeByRaftAddr(string(leaderAddr))
+	if !ok || node.HTTPAddr == "" {
+		return "", fmt.Errorf("leader %s has not registered an HTTP address", leaderAddr)
+	}
+	return node.HTTPAddr, nil
+}
+
+// Members returns the IDs of every server in the current Raft configuration,
+// voters and non-voting worker-only nodes alike. These are the nodes that run jobs.
+func (n *RaftNode) Members() ([]string, error) {
+	future := n.Raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, server := range future.Configuration().Servers {
+		if server.Suffrage == raft.Voter || server.Suffrage == raft.Nonvoter {
+			ids = append(ids, string(server.ID))
+		}
+	}
+	return ids, nil
+}
+
+// RegisterSelf records this node's addresses in the replicated node registry.
+// Only the leader can apply it, so it is called whenever leadership is acquired.
+func (n *RaftNode) RegisterSelf(nodeID, raftAddr, httpAddr string) error {
+	if existing, ok := n.FSM.state.GetNode(nodeID); ok &&
+		existing.RaftAddr == raftAddr && existing.HTTPAddr == httpAddr {
+		return nil
+	}
+	event := LogEvent{
+		Type: CmdSetNode,
+		Node: &store.Node{ID: nodeID, RaftAddr: raftAddr, HTTPAddr: httpAddr},
+	}
+	return n.Raft.Apply(MustMarshalEvent(event), 5*time.Second).Error()
+}
+
+// ApplyEvent proposes event through Raft and waits for it to be applied.
+// An error returned by FSM.Apply (e.g. ErrJobNotFound) is passed through.
+func (n *RaftNode) ApplyEvent(event LogEvent, timeout time.Duration) error {
+	future := n.Raft.Apply(MustMarshalEvent(event), timeout)
+	if err := future.Error(); err != nil {
+		return err
+	}
+	switch resp := future.Response().(type) {
+	case error:
+		return resp
+	case *UpdateResult:
+		return resp.Err
+	}
+	return nil
+}
+
+// UpdateJob proposes event as an UPDATE_JOB: event.Job replaces the current
+// job if event.Precondition holds (see Precondition). Cause and Reason go into
+// the job's history. It returns the job as committed, or as it currently is
+// together with an error wrapping ErrPreconditionFailed when the precondition
+// did not hold.
+func (n *RaftNode) UpdateJob(event LogEvent, timeout time.Duration) (*store.Job, error) {
+	event.Type = CmdUpdateJob
+	event.JobID = event.Job.ID
+	return n.applyJobCommand(event, timeout)
+}
+
+// FailJob proposes event as a FAIL_JOB for event.JobID (see FSM.applyFail).
+// It returns the job as committed: PENDING if it will be retried, FAILED otherwise.
+func (n *RaftNode) FailJob(event LogEvent, timeout time.Duration) (*store.Job, error) {
+	event.Type = CmdFailJob
+	return n.applyJobCommand(event, timeout)
+}
+
+// RequeueJob proposes event as a REQUEUE_JOB for event.JobID (see FSM.applyRequeue).
+// It returns the job as committed, or an error wrapping ErrPreconditionFailed
+// if the job is not FAILED.
+func (n *RaftNode) RequeueJob(event LogEvent, timeout time.Duration) (*store.Job, error) {
+	event.Type = CmdRequeueJob
+	return n.applyJobCommand(event, timeout)
+}
+
+// RecordAggregation proposes a RECORD_AGGREGATION for a.ParentID. It returns
+// an error wrapping ErrAlreadyAggregated if the parent was recorded before.
+func (n *RaftNode) RecordAggregation(a *store.Aggregation, timeout time.Duration) error {
+	return n.ApplyEvent(LogEvent{
+		Type:        CmdAggregate,
+		JobID:       a.ParentID,
+		Aggregation: a,
+		Timestamp:   time.Now().Unix(),
+	}, timeout)
+}
+
+// AdvanceRound proposes an ADVANCE_ROUND for training run id: round is how
+// its current round ended, and workers run the next one if there is one. It
+// returns an error wrapping ErrRoundAdvanced if that round was advanced before.
+func (n *RaftNode) AdvanceRound(id string, round *store.Round, workers []string, timeout time.Duration) error {
+	return n.ApplyEvent(LogEvent{
+		Type:      CmdAdvanceRound,
+		JobID:     id,
+		Round:     round,
+		Workers:   workers,
+		Timestamp: time.Now().Unix(),
+	}, timeout)
+}
+
+// applyJobCommand proposes a command that answers with an *UpdateResult
+func (n *RaftNode) applyJobCommand(event LogEvent, timeout time.Duration) (*store.Job, error) {
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().Unix()
+	}
+	future := n.Raft.Apply(MustMarshalEvent(event), timeout)
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	switch resp := future.Response().(type) {
+	case *UpdateResult:
+		return resp.Job, resp.Err
+	case error:
+		return nil, resp
+	}
+	return nil, fmt.Errorf("unexpected response to %s: %T", event.Type, future.Response())
+}
+
+// ConsistentRead makes the local FSM safe for a linearizable read.
+// The barrier waits until every entry committed before the read has been applied,
+// and VerifyLeader confirms a quorum still follows us, so no newer leader can
+// have accepted writes this node has not seen.
+func (n *RaftNode) ConsistentRead(timeout time.Duration) error {
+	if err := n.Raft.Barrier(timeout).Error(); err != nil {
+		return err
+	}
+	return n.Raft.VerifyLeader().Error()
+}
+
+// RemoveNode removes a member from the Raft configuration, then from the node
+// registry, so the registry only drops nodes that really left. The leader
+// cannot remove itself (it steps down and could not commit the registry
+// change): transfer leadership first and have the new leader remove it.
+func (n *RaftNode) RemoveNode(nodeID string) error {
+	if _, leaderID := n.Raft.LeaderWithID(); leaderID == raft.ServerID(nodeID) {
+		return fmt.Errorf("%s is the leader: transfer leadership before removing it", nodeID)
+	}
+	if err := n.Raft.RemoveServer(raft.ServerID(nodeID), 0, 0).Error(); err != nil {
+		return err
+	}
+	event := LogEvent{Type: CmdRemoveNode, Node: &store.Node{ID: nodeID}}
+	return n.Raft.Apply(MustMarshalEvent(event), 5*time.Second).Error()
 }
 
 // Close gracefully shuts down the Raft node and closes all file handles.
@@ -68,6 +257,9 @@ func (n *RaftNode) Close() error {
 	if err := n.Raft.Shutdown().Error(); err != nil {
 		return err
 	}
+	if n.liveness != nil {
+		n.liveness.stop(n.Raft)
+	}
 	// Then close the log store (which is also the stable store)
 	if closer, ok := n.logStore.(interface{ Close() error }); ok {
 		return closer.Close()
@@ -1,125 +1,190 @@
 package master
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
-	"os/exec"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 )
 
-// RunAggregator periodically checks for completed sub-jobs and merges their models.
-func RunAggregator(state *store.State, parentPrefix string, pollInterval time.Duration) {
+// ModelWriteGrace is how long after its last write a malformed model is still
+// taken to be half-written, rather than broken, and its merge retried
+const ModelWriteGrace = time.Minute
+
+// RunAggregator periodically merges the models of parent jobs whose sub-jobs
+// have all completed. Only the leader merges, and it records every merge
+// through Raft (RECORD_AGGREGATION), so each parent is merged once, also
+// across leader changes: a parent with a recorded aggregation is never merged
+// again. Only if a leader dies between merging and committing the record does
+// its successor run the merge a second time. Global models go in dataDir.
+func RunAggregator(state *store.State, rNode *consensus.RaftNode, dataDir, parentPrefix string, pollInterval time.Duration) {
+	leaderLoop(rNode, "Aggregator", pollInterval, func() {
+		for parent, models := range ReadyParents(state, parentPrefix) {
+			var cfg *store.AggregatorConfig
+			if p, ok := state.GetParent(parent); ok {
+				cfg = p.Aggregator
+			}
+			result, err := MergeModels(dataDir, parent, cfg, models)
+			if err != nil {
+				// Not recorded, so the parent stays ready and is merged again next poll
+				log.Printf("⚠️ Aggregator: cannot merge %s yet, will retry: %v", parent, err)
+				continue
+			}
+			err = rNode.RecordAggregation(result, 5*time.Second)
+			switch {
+			case errors.Is(err, consensus.ErrAlreadyAggregated):
+				log.Printf("⏭️ Aggregator: %v", err)
+			case err != nil:
+				log.Printf("❌ Aggregator: failed to record aggregation of %s: %v", parent, err)
+			case result.Status == store.AggregationMerged:
+				log.Printf("Aggregator: merged %d models for %s with %s -> %s", result.NumModels, parent, result.Strategy, result.ModelPath)
+			default:
+				log.Printf("❌ Aggregator: merging %s failed: %s", parent, result.Error)
+			}
+		}
+	})
+}
+
+// leaderLoop calls step every pollInterval while rNode is the leader. A new
+// leader may not have applied its predecessor's last entries yet, so at the
+// start of each term it first catches up.
+func leaderLoop(rNode *consensus.RaftNode, name string, pollInterval time.Duration, step func()) {
 	if pollInterval <= 0 {
 		pollInterval = 2 * time.Second
 	}
+	var syncedTerm uint64
 	for {
 		time.Sleep(pollInterval)
 
-		state.RLock()
-		jobs := make(map[string]*store.Job)
-		for id, job := range state.Jobs {
-			jobs[id] = job
+		if !rNode.IsLeader() {
+			continue
 		}
-		state.RUnlock()
-
-		parents := collectParents(jobs, parentPrefix)
-		for _, parent := range parents {
-			subIDs := []string{fmt.Sprintf("%s-node-1", parent), fmt.Sprintf("%s-node-2", parent), fmt.Sprintf("%s-node-3", parent)}
-			models := make([]string, 0, 3)
-			allDone := true
-			expectedShards := len(subIDs)
-			foundShards := 0
-			
-			for _, sid := range subIDs {
-				state.RLock()
-				job, ok := state.Jobs[sid]
-				state.RUnlock()
-				if !ok || job.Status != store.StatusCompleted || job.ResultURL == "" {
-					allDone = false
-					break
-				}
-				models = append(models, job.ResultURL)
-				foundShards++
-			}
-			
-			// Validate all expected shards are present
-			if !allDone {
-				continue
-			}
-			
-			if foundShards != expectedShards {
-				log.Printf("⚠️ Aggregator: skipping %s - only %d/%d shards completed", parent, foundShards, expectedShards)
+		if term := rNode.Raft.CurrentTerm(); term != syncedTerm {
+			if err := rNode.ConsistentRead(5 * time.Second); err != nil {
+				log.Printf("⚠️ %s: failed to catch up after becoming leader: %v", name, err)
 				continue
 			}
+			syncedTerm = term
+		}
+		step()
+	}
+}
 
-			outPath := filepath.Join("raft-data", fmt.Sprintf("%s_global.pth", parent))
-			args := append([]string{"ml-code/merge.py", parent, "--models"}, models...)
-			args = append(args, "--out", outPath)
-			cmd := exec.Command("python3", args...)
-			stdout, _ := cmd.StdoutPipe()
-			cmd.Stderr = cmd.Stderr
-			if err := cmd.Start(); err != nil {
-				log.Printf("Aggregator: failed to start merge.py: %v", err)
-				continue
+// ReadyParents returns the sub-job models, in shard order, of every parent
+// whose sub-jobs have all completed and whose aggregation was not recorded yet
+func ReadyParents(state *store.State, parentPrefix string) map[string][]string {
+	ready := map[string][]string{}
+	for _, parent := range state.GetParentsByStatus(store.ParentCompleted) {
+		if parentPrefix != "" && !strings.HasPrefix(parent.ID, parentPrefix) {
+			continue
+		}
+		models := make([]string, 0, len(parent.ChildIDs))
+		for _, childID := range parent.ChildIDs {
+			if job, ok := state.GetJob(childID); ok && job.ResultURL != "" {
+				models = append(models, job.ResultURL)
 			}
+		}
+		if len(models) != len(parent.ChildIDs) {
+			log.Printf("⚠️ Aggregator: skipping %s - only %d/%d shards have a model", parent.ID, len(models), len(parent.ChildIDs))
+			continue
+		}
+		ready[parent.ID] = models
+	}
+	return ready
+}
 
-			var lastLine string
-			buf := make([]byte, 4096)
-			for {
-				n, err := stdout.Read(buf)
-				if n > 0 {
-					for _, line := range strings.Split(string(buf[:n]), "\n") {
-						line = strings.TrimSpace(line)
-						if line != "" {
-							lastLine = line
-							log.Printf("[Aggregator - %s] %s", parent, line)
-						}
-					}
-				}
-				if err != nil {
-					break
-				}
-			}
-			if err := cmd.Wait(); err != nil {
-				log.Printf("Aggregator: merge.py crashed: %v", err)
-				continue
-			}
+// MergeModels merges models into the parent's global model with the
+// aggregator cfg selects, under dataDir, and describes the outcome, to be recorded. Reading
+// or writing files can fail for reasons that go away (e.g. a model not on
+// shared storage yet, or one still being written), so such errors are
+// returned instead and the merge should be tried again; a FAILED outcome is
+// for models or configs that can never be merged.
+func MergeModels(dataDir, parent string, cfg *store.AggregatorConfig, models []string) (*store.Aggregation, error) {
+	result := &store.Aggregation{ParentID: parent, Status: store.AggregationFailed, NumModels: len(models)}
+	fail := func(err error) (*store.Aggregation, error) {
+		if pathErr := (*fs.PathError)(nil); errors.As(err, &pathErr) {
+			return nil, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
 
-			var result struct {
-				ParentID  string `json:"parent_id"`
-				Status    string `json:"status"`
-				ModelPath string `json:"model_path"`
-				NumModels int    `json:"num_models"`
-			}
-			if err := json.Unmarshal([]byte(lastLine), &result); err != nil {
-				log.Printf("Aggregator: failed to parse merge output: %v", err)
-				continue
-			}
-			log.Printf("Aggregator: merged %d models for %s -> %s", result.NumModels, parent, result.ModelPath)
+	agg, err := NewAggregator(cfg)
+	if err != nil {
+		return fail(err)
+	}
+	result.Strategy = agg.Name()
+
+	loaded := make([]*aggregation.Model, 0, len(models))
+	var samples float64
+	for _, path := range models {
+		m, err := aggregation.ReadFile(path)
+		if errors.Is(err, aggregation.ErrMalformed) && recentlyWritten(path) {
+			return nil, err
 		}
+		if err != nil {
+			return fail(err)
+		}
+		loaded = append(loaded, m)
+		samples += aggregation.SampleWeight(m)
+	}
+	global, err := agg.Aggregate(loaded)
+	if err != nil {
+		return fail(err)
+	}
+	global.Metadata = map[string]string{
+		aggregation.MetaNumModels:  strconv.Itoa(len(models)),
+		aggregation.MetaNumSamples: strconv.FormatFloat(samples, 'f', -1, 64),
+		aggregation.MetaAggregator: agg.Name(),
+	}
+
+	outPath := GlobalModelPath(dataDir, parent)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return fail(err)
+	}
+	if err := aggregation.WriteFile(outPath, global); err != nil {
+		return fail(err)
 	}
+	result.Status = store.AggregationMerged
+	result.ModelPath = outPath
+	return result, nil
 }
 
-// collectParents finds unique parent IDs from jobs ending in -node-1/2/3.
-func collectParents(jobs map[string]*store.Job, parentPrefix string) []string {
-	parents := map[string]struct{}{}
-	for id := range jobs {
-		if parentPrefix != "" && !strings.HasPrefix(id, parentPrefix) {
+// recentlyWritten reports whether path changed within ModelWriteGrace
+func recentlyWritten(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) < ModelWriteGrace
+}
+
+// GlobalModelPath is where the merged model of parent is written under dataDir
+func GlobalModelPath(dataDir, parent string) string {
+	return filepath.Join(dataDir, fmt.Sprintf("%s_global.safetensors", parent))
+}
+
+// CollectParents groups sub-jobs by their parent job ID, ordered by shard index.
+func CollectParents(jobs map[string]*store.Job, parentPrefix string) map[string][]*store.Job {
+	parents := map[string][]*store.Job{}
+	for _, job := range jobs {
+		if job.ParentID == "" {
 			continue
 		}
-		if strings.HasSuffix(id, "-node-1") || strings.HasSuffix(id, "-node-2") || strings.HasSuffix(id, "-node-3") {
-			p := id[:len(id)-7]
-			parents[p] = struct{}{}
+		if parentPrefix != "" && !strings.HasPrefix(job.ParentID, parentPrefix) {
+			continue
 		}
+		parents[job.ParentID] = append(parents[job.ParentID], job)
 	}
-	out := make([]string, 0, len(parents))
-	for p := range parents {
-		out = append(out, p)
+	for _, subJobs := range parents {
+		sort.Slice(subJobs, func(i, j int) bool { return subJobs[i].ShardIndex < subJobs[j].ShardIndex })
 	}
-	return out
+	return parents
 }
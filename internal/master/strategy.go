@@ -0,0 +1,151 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// Aggregator names, as given in a parent job's "aggregator" config
+const (
+	AggregatorFedAvg      = "fedavg"
+	AggregatorMedian      = "median"
+	AggregatorTrimmedMean = "trimmed_mean"
+	AggregatorKrum        = "krum"
+)
+
+// DefaultTrimFraction is the share of models TrimmedMean drops at each end
+// when the config does not say
+const DefaultTrimFraction = 0.2
+
+// ErrUnknownAggregator is returned for configs naming no built-in aggregator
+var ErrUnknownAggregator = errors.New("unknown aggregator")
+
+// Aggregator merges the models trained by a parent job's sub-jobs, given in
+// shard order, into its global model
+type Aggregator interface {
+	Name() string
+	Aggregate(models []*aggregation.Model) (*aggregation.Model, error)
+}
+
+// NewAggregator returns the aggregator cfg selects; a nil config or empty
+// name selects FedAvg. Parameters that make no sense for any number of
+// models are rejected here, so a bad config fails at submission.
+func NewAggregator(cfg *store.AggregatorConfig) (Aggregator, error) {
+	if cfg == nil {
+		return FedAvg{}, nil
+	}
+	switch cfg.Name {
+	case "", AggregatorFedAvg:
+		return FedAvg{}, nil
+	case AggregatorMedian:
+		return Median{}, nil
+	case AggregatorTrimmedMean:
+		if cfg.TrimFraction < 0 || cfg.TrimFraction >= 0.5 {
+			return nil, fmt.Errorf("trim_fraction must be in [0, 0.5), got %g", cfg.TrimFraction)
+		}
+		fraction := cfg.TrimFraction
+		if fraction == 0 {
+			fraction = DefaultTrimFraction
+		}
+		return TrimmedMean{Fraction: fraction}, nil
+	case AggregatorKrum:
+		if cfg.Byzantine < 0 {
+			return nil, fmt.Errorf("byzantine must not be negative, got %d", cfg.Byzantine)
+		}
+		return Krum{Byzantine: cfg.Byzantine}, nil
+	}
+	return nil, fmt.Errorf("%w %q (want %s, %s, %s or %s)", ErrUnknownAggregator, cfg.Name,
+		AggregatorFedAvg, AggregatorMedian, AggregatorTrimmedMean, AggregatorKrum)
+}
+
+// ValidateAggregator reports why the aggregator cfg selects cannot merge the
+// models of n shards, or nil. A parent is split into one shard per worker, so
+// submissions check this against the workers they are split across.
+func ValidateAggregator(cfg *store.AggregatorConfig, n int) error {
+	agg, err := NewAggregator(cfg)
+	if err != nil {
+		return err
+	}
+	switch a := agg.(type) {
+	case TrimmedMean:
+		if trim := a.trim(n); n-2*trim < 1 {
+			return fmt.Errorf("trim_fraction %g drops all %d models (%d from each end)", a.Fraction, n, trim)
+		}
+	case Krum:
+		if b := a.byzantine(n); n < 2*b+3 {
+			return fmt.Errorf("krum needs at least %d models to tolerate %d faulty ones, got %d", 2*b+3, b, n)
+		}
+	}
+	return nil
+}
+
+// FedAvg averages the models weighted by the number of samples each was
+// trained on. It is the default, and the only aggregator that uses every
+// model as is, so one poisoned shard moves the global model arbitrarily far.
+type FedAvg struct{}
+
+func (FedAvg) Name() string { return AggregatorFedAvg }
+
+func (FedAvg) Aggregate(models []*aggregation.Model) (*aggregation.Model, error) {
+	weights := make([]float64, len(models))
+	for i, m := range models {
+		weights[i] = aggregation.SampleWeight(m)
+	}
+	return aggregation.FedAvg(models, weights)
+}
+
+// Median takes the coordinate-wise median, ignoring sample counts. It
+// withstands fewer than half of the models being arbitrary.
+type Median struct{}
+
+func (Median) Name() string { return AggregatorMedian }
+
+func (Median) Aggregate(models []*aggregation.Model) (*aggregation.Model, error) {
+	return aggregation.Median(models)
+}
+
+// TrimmedMean drops, per coordinate, the largest and smallest
+// round(Fraction * len(models)) values and averages the rest
+type TrimmedMean struct {
+	Fraction float64
+}
+
+func (TrimmedMean) Name() string { return AggregatorTrimmedMean }
+
+func (t TrimmedMean) Aggregate(models []*aggregation.Model) (*aggregation.Model, error) {
+	return aggregation.TrimmedMean(models, t.trim(len(models)))
+}
+
+// trim is how many of n values are dropped at each end
+func (t TrimmedMean) trim(n int) int {
+	return int(math.Round(t.Fraction * float64(n)))
+}
+
+// Krum picks the single model closest to its neighbours (see
+// aggregation.Krum). Byzantine is the number of faulty models to tolerate;
+// 0 tolerates as many as the number of models allows, (len(models)-3)/2.
+type Krum struct {
+	Byzantine int
+}
+
+func (Krum) Name() string { return AggregatorKrum }
+
+func (k Krum) Aggregate(models []*aggregation.Model) (*aggregation.Model, error) {
+	chosen, err := aggregation.Krum(models, k.byzantine(len(models)))
+	if err != nil {
+		return nil, err
+	}
+	return models[chosen].Clone(), nil
+}
+
+// byzantine is how many of n models are tolerated as faulty
+func (k Krum) byzantine(n int) int {
+	if k.Byzantine == 0 {
+		return max((n-3)/2, 0)
+	}
+	return k.Byzantine
+}
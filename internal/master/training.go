@@ -0,0 +1,126 @@
+package master
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// RunTrainer drives multi-round training runs. Each round is a parent job
+// that the aggregator merges like any other; once a round is over, the
+// leader measures it and proposes ADVANCE_ROUND, which records the metrics
+// and starts the next round from the new global model, or ends the run.
+// Rounds, metrics and the current round all live in the FSM, so a new leader
+// carries on mid-round where its predecessor stopped.
+func RunTrainer(state *store.State, rNode *consensus.RaftNode, pollInterval time.Duration) {
+	leaderLoop(rNode, "Trainer", pollInterval, func() {
+		for _, run := range state.GetTrainingsByStatus(store.TrainingRunning) {
+			round, err := FinishedRound(state, run)
+			if err != nil {
+				log.Printf("⚠️ Trainer: cannot measure round of %s: %v", run.ID, err)
+				continue
+			}
+			if round == nil {
+				continue
+			}
+			// The next round is split across the current members, like a new submission
+			workers, err := rNode.Members()
+			if err != nil {
+				log.Printf("⚠️ Trainer: failed to read cluster configuration: %v", err)
+				continue
+			}
+			err = rNode.AdvanceRound(run.ID, round, workers, 5*time.Second)
+			switch {
+			case errors.Is(err, consensus.ErrRoundAdvanced):
+				log.Printf("⏭️ Trainer: %v", err)
+			case err != nil:
+				log.Printf("❌ Trainer: failed to advance %s past round %d: %v", run.ID, round.Round, err)
+			default:
+				log.Printf("Trainer: %s round %d %s (loss %.4f, update %.4f)", run.ID, round.Round, round.Status, round.Loss, round.UpdateNorm)
+			}
+		}
+	})
+}
+
+// FinishedRound returns the outcome and metrics of run's current round once
+// the round is over, or nil while it is still going. A round is over when
+// its parent job was merged, failed, or was cancelled, or when some of its
+// sub-jobs failed for good and none is left running. An error means the
+// round is over but could not be measured; the caller should try again.
+func FinishedRound(state *store.State, run *store.TrainingRun) (*store.Round, error) {
+	current := run.CurrentRound()
+	if current == nil {
+		return nil, nil
+	}
+	parent, ok := state.GetParent(current.ParentID)
+	if !ok {
+		return nil, nil
+	}
+
+	round := &store.Round{Round: current.Round, Status: parent.Status}
+	switch parent.Status {
+	case store.ParentMerged:
+		if err := measureRound(state, parent, current.InitialModel, round); err != nil {
+			return nil, err
+		}
+	case store.ParentFailed:
+		round.Error = parent.AggregationError
+		if round.Error == "" {
+			round.Error = "every sub-job failed"
+		}
+	case store.ParentPartiallyFailed:
+		if parent.Progress.Pending+parent.Progress.Running > 0 {
+			return nil, nil
+		}
+		round.Status = store.ParentFailed
+		round.Error = fmt.Sprintf("%d of %d sub-jobs failed", parent.Progress.Failed, parent.Progress.Total)
+	case store.ParentCancelled:
+	default:
+		return nil, nil
+	}
+	return round, nil
+}
+
+// measureRound fills in the metrics of a merged round: its global model,
+// the sample-weighted loss and accuracy its sub-jobs reported, and how far
+// it moved the model it started from
+func measureRound(state *store.State, parent *store.ParentJob, initialModel string, round *store.Round) error {
+	round.ModelPath = parent.GlobalModelPath
+	round.NumModels = parent.NumModels
+
+	var loss, accuracy, weights float64
+	for _, childID := range parent.ChildIDs {
+		job, ok := state.GetJob(childID)
+		if !ok {
+			continue
+		}
+		w := float64(max(job.NumSamples, 1))
+		round.NumSamples += job.NumSamples
+		loss += w * job.Loss
+		accuracy += w * job.Accuracy
+		weights += w
+	}
+	if weights > 0 {
+		round.Loss = loss / weights
+		round.Accuracy = accuracy / weights
+	}
+
+	if initialModel == "" {
+		return nil
+	}
+	before, err := aggregation.ReadFile(initialModel)
+	if err != nil {
+		return err
+	}
+	after, err := aggregation.ReadFile(round.ModelPath)
+	if err != nil {
+		return err
+	}
+	round.UpdateNorm, err = aggregation.Distance(before, after)
+	return err
+}
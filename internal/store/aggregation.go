@@ -0,0 +1,61 @@
+package store
+
+// AggregationStatus is the outcome of merging a parent job's models
+type AggregationStatus string
+
+const (
+	AggregationMerged AggregationStatus = "MERGED"
+	AggregationFailed AggregationStatus = "FAILED"
+)
+
+// Aggregation records the merge of a parent job's sub-job models. Once
+// recorded it is final: the leader never merges that parent again.
+type Aggregation struct {
+	ParentID  string            `json:"parent_id"`
+	Status    AggregationStatus `json:"status"`
+	ModelPath string            `json:"model_path,omitempty"` // Merged (global) model
+	NumModels int               `json:"num_models"`           // Sub-job models that went into it
+	Strategy  string            `json:"strategy,omitempty"`   // Aggregator that merged them
+	Error     string            `json:"error,omitempty"`      // Why the merge failed, for FAILED
+	Time      int64             `json:"time,omitempty"`       // Unix time chosen by the proposer
+	Index     uint64            `json:"index,omitempty"`      // Raft log entry that recorded it
+	Term      uint64            `json:"term,omitempty"`
+}
+
+// AggregatorConfig picks how a parent job's models are merged (see
+// master.NewAggregator). The zero value, or a nil config, means FedAvg.
+type AggregatorConfig struct {
+	Name         string  `json:"name,omitempty"`          // fedavg, median, trimmed_mean or krum
+	TrimFraction float64 `json:"trim_fraction,omitempty"` // trimmed_mean: share of models dropped at each end
+	Byzantine    int     `json:"byzantine,omitempty"`     // krum: faulty models to tolerate
+}
+
+// Clone returns a copy of the config
+func (c *AggregatorConfig) Clone() *AggregatorConfig {
+	if c == nil {
+		return nil
+	}
+	copied := *c
+	return &copied
+}
+
+// SetAggregation records the aggregation of a.ParentID
+func (s *State) SetAggregation(a *Aggregation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *a
+	s.aggregations[a.ParentID] = &stored
+	s.refreshParent(LogPosition{Index: a.Index, Term: a.Term}, a.ParentID)
+}
+
+// GetAggregation returns a copy of parentID's aggregation, if it has one
+func (s *State) GetAggregation(parentID string) (*Aggregation, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.aggregations[parentID]
+	if !ok {
+		return nil, false
+	}
+	c := *a
+	return &c, true
+}
@@ -0,0 +1,23 @@
+package store
+
+// DeadLetter is a job that failed for good, with the history of every attempt
+type DeadLetter struct {
+	Job    *Job       `json:"job"`
+	Events []JobEvent `json:"events"`
+}
+
+// DeadLetters returns one page of FAILED jobs matching q (its Status is
+// ignored) with their histories, plus the cursor for the next page.
+// Jobs still being retried are PENDING or RUNNING and do not show up here.
+func (s *State) DeadLetters(q JobQuery) ([]DeadLetter, string, error) {
+	q.Status = StatusFailed
+	jobs, next, err := s.ListJobs(q)
+	if err != nil {
+		return nil, "", err
+	}
+	letters := make([]DeadLetter, 0, len(jobs))
+	for _, job := range jobs {
+		letters = append(letters, DeadLetter{Job: job, Events: s.GetJobEvents(job.ID)})
+	}
+	return letters, next, nil
+}
@@ -0,0 +1,44 @@
+package store
+
+// JobEventType names an entry in a job's history
+type JobEventType string
+
+const (
+	EventCreated       JobEventType = "CREATED"
+	EventClaimed       JobEventType = "CLAIMED"
+	EventRunning       JobEventType = "RUNNING" // Set to RUNNING without a claim
+	EventHeartbeatLost JobEventType = "HEARTBEAT_LOST"
+	EventReassigned    JobEventType = "REASSIGNED"
+	EventRequeued      JobEventType = "REQUEUED" // Back to PENDING on the same worker
+	EventFailed        JobEventType = "FAILED"
+	EventCompleted     JobEventType = "COMPLETED"
+	EventCancelled     JobEventType = "CANCELLED"
+)
+
+// JobEvent is one entry in a job's append-only history
+type JobEvent struct {
+	Type       JobEventType `json:"type"`
+	Time       int64        `json:"time,omitempty"`  // Unix time chosen by the proposer
+	Index      uint64       `json:"index,omitempty"` // Raft log entry that caused the event
+	Term       uint64       `json:"term,omitempty"`
+	WorkerID   string       `json:"worker_id,omitempty"`   // Worker the job is on afterwards
+	FromWorker string       `json:"from_worker,omitempty"` // Previous worker, for REASSIGNED
+	Reason     string       `json:"reason,omitempty"`
+}
+
+// AppendJobEvents adds events to the end of jobID's history
+func (s *State) AppendJobEvents(jobID string, events ...JobEvent) {
+	if len(events) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[jobID] = append(s.events[jobID], events...)
+}
+
+// GetJobEvents returns a copy of jobID's history, oldest first
+func (s *State) GetJobEvents(jobID string) []JobEvent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]JobEvent(nil), s.events[jobID]...)
+}
@@ -0,0 +1,348 @@
+package store
+
+import (
+	"cmp"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JobQuery selects and pages through jobs. Empty filter fields match everything.
+type JobQuery struct {
+	Status   JobStatus
+	WorkerID string
+	ParentID string
+	Type     string
+
+	Ascending bool   // Oldest UpdatedAt first (default: newest first)
+	Cursor    string // NextCursor from the previous page
+	Limit     int    // Page size; 0 means DefaultPageSize
+}
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// jobIndex keeps secondary indexes from field value to job IDs, and the
+// same selections sorted by (UpdatedAt, ID) so ListJobs can seek to a cursor
+// instead of sorting every match. The keys a job was indexed under are
+// remembered separately, so removing an entry does not depend on the previous
+// *Job still being around.
+type jobIndex struct {
+	byStatus map[string]map[string]struct{}
+	byWorker map[string]map[string]struct{}
+	byParent map[string]map[string]struct{}
+	byType   map[string]map[string]struct{}
+	sorted   map[sortKey]sortedJobs
+	keys     map[string]indexKeys
+}
+
+type indexKeys struct {
+	status, worker, parent, jobType string
+	updatedAt                       int64
+}
+
+// sortKey names one sorted selection: every job (field ""), or the jobs with
+// the given value of a filter field
+type sortKey struct {
+	field, value string
+}
+
+// statusWorker is the sorted selection for a status and worker together,
+// which is what a worker looking for its next job asks for
+const statusWorker = "status+worker"
+
+func (k indexKeys) sortKeys() []sortKey {
+	return []sortKey{
+		{},
+		{"status", k.status},
+		{"worker", k.worker},
+		{"parent", k.parent},
+		{"type", k.jobType},
+		{statusWorker, k.status + "\x00" + k.worker},
+	}
+}
+
+func newJobIndex() *jobIndex {
+	return &jobIndex{
+		byStatus: make(map[string]map[string]struct{}),
+		byWorker: make(map[string]map[string]struct{}),
+		byParent: make(map[string]map[string]struct{}),
+		byType:   make(map[string]map[string]struct{}),
+		sorted:   make(map[sortKey]sortedJobs),
+		keys:     make(map[string]indexKeys),
+	}
+}
+
+// update re-indexes jobID under the job's current field values
+func (ix *jobIndex) update(jobID string, job *Job) {
+	ix.remove(jobID)
+	if job == nil {
+		return
+	}
+	k := indexKeys{status: string(job.Status), worker: job.WorkerID, parent: job.ParentID, jobType: job.Type, updatedAt: job.UpdatedAt}
+	addToSet(ix.byStatus, k.status, jobID)
+	addToSet(ix.byWorker, k.worker, jobID)
+	addToSet(ix.byParent, k.parent, jobID)
+	addToSet(ix.byType, k.jobType, jobID)
+	pos := cursorPos{updatedAt: k.updatedAt, id: jobID}
+	for _, key := range k.sortKeys() {
+		ix.sorted[key] = ix.sorted[key].insert(pos)
+	}
+	ix.keys[jobID] = k
+}
+
+func (ix *jobIndex) remove(jobID string) {
+	k, ok := ix.keys[jobID]
+	if !ok {
+		return
+	}
+	removeFromSet(ix.byStatus, k.status, jobID)
+	removeFromSet(ix.byWorker, k.worker, jobID)
+	removeFromSet(ix.byParent, k.parent, jobID)
+	removeFromSet(ix.byType, k.jobType, jobID)
+	pos := cursorPos{updatedAt: k.updatedAt, id: jobID}
+	for _, key := range k.sortKeys() {
+		if rest := ix.sorted[key].delete(pos); len(rest) > 0 {
+			ix.sorted[key] = rest
+		} else {
+			delete(ix.sorted, key)
+		}
+	}
+	delete(ix.keys, jobID)
+}
+
+// selection returns the shortest sorted selection covering the query: the
+// one for its most selective filter, or every job if it has none
+func (ix *jobIndex) selection(q JobQuery) sortedJobs {
+	var keys []sortKey
+	if q.Status != "" && q.WorkerID != "" {
+		keys = append(keys, sortKey{statusWorker, string(q.Status) + "\x00" + q.WorkerID})
+	}
+	if q.Status != "" {
+		keys = append(keys, sortKey{"status", string(q.Status)})
+	}
+	if q.WorkerID != "" {
+		keys = append(keys, sortKey{"worker", q.WorkerID})
+	}
+	if q.ParentID != "" {
+		keys = append(keys, sortKey{"parent", q.ParentID})
+	}
+	if q.Type != "" {
+		keys = append(keys, sortKey{"type", q.Type})
+	}
+	if len(keys) == 0 {
+		return ix.sorted[sortKey{}]
+	}
+	best := ix.sorted[keys[0]]
+	for _, key := range keys[1:] {
+		if list := ix.sorted[key]; len(list) < len(best) {
+			best = list
+		}
+	}
+	return best
+}
+
+func addToSet(idx map[string]map[string]struct{}, key, jobID string) {
+	set, ok := idx[key]
+	if !ok {
+		set = make(map[string]struct{})
+		idx[key] = set
+	}
+	set[jobID] = struct{}{}
+}
+
+func removeFromSet(idx map[string]map[string]struct{}, key, jobID string) {
+	set := idx[key]
+	delete(set, jobID)
+	if len(set) == 0 {
+		delete(idx, key)
+	}
+}
+
+// matches reports whether job satisfies every filter in q
+func (q JobQuery) matches(job *Job) bool {
+	return (q.Status == "" || job.Status == q.Status) &&
+		(q.WorkerID == "" || job.WorkerID == q.WorkerID) &&
+		(q.ParentID == "" || job.ParentID == q.ParentID) &&
+		(q.Type == "" || job.Type == q.Type)
+}
+
+// ListJobs returns one page of jobs matching q sorted by UpdatedAt (ties broken
+// by ID), plus the cursor for the next page ("" when there are no more).
+// It seeks to the cursor in the selection for the query's most selective
+// filter and scans only until the page is full.
+func (s *State) ListJobs(q JobQuery) ([]*Job, string, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+
+	var after *cursorPos
+	if q.Cursor != "" {
+		pos, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &pos
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page := make([]*Job, 0, min(limit, len(s.jobs)))
+	more := false
+	visit := func(pos cursorPos) bool {
+		job, ok := s.jobs[pos.id]
+		if !ok || !q.matches(job) {
+			return true
+		}
+		if len(page) == limit {
+			more = true
+			return false
+		}
+		page = append(page, job.Clone())
+		return true
+	}
+	if q.Ascending {
+		s.index.selection(q).walkAscending(after, visit)
+	} else {
+		s.index.selection(q).walkDescending(after, visit)
+	}
+
+	next := ""
+	if more {
+		next = encodeCursor(positionOf(page[len(page)-1]))
+	}
+	return page, next, nil
+}
+
+// WorkerLoad returns the number of PENDING and RUNNING jobs assigned to each worker
+func (s *State) WorkerLoad() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	load := make(map[string]int)
+	for _, status := range []JobStatus{StatusPending, StatusRunning} {
+		for id := range s.index.byStatus[string(status)] {
+			load[s.index.keys[id].worker]++
+		}
+	}
+	return load
+}
+
+// cursorPos is the sort key of the last job on a page
+type cursorPos struct {
+	updatedAt int64
+	id        string
+}
+
+func positionOf(job *Job) cursorPos {
+	return cursorPos{updatedAt: job.UpdatedAt, id: job.ID}
+}
+
+func comparePos(a, b cursorPos) int {
+	if c := cmp.Compare(a.updatedAt, b.updatedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.id, b.id)
+}
+
+// sortedJobs holds job positions in ascending (UpdatedAt, ID) order
+type sortedJobs []cursorPos
+
+func (l sortedJobs) insert(pos cursorPos) sortedJobs {
+	i, found := slices.BinarySearchFunc(l, pos, comparePos)
+	if found {
+		return l
+	}
+	return slices.Insert(l, i, pos)
+}
+
+func (l sortedJobs) delete(pos cursorPos) sortedJobs {
+	i, found := slices.BinarySearchFunc(l, pos, comparePos)
+	if !found {
+		return l
+	}
+	return slices.Delete(l, i, i+1)
+}
+
+// firstAfter returns the index of the first position after pos
+func (l sortedJobs) firstAfter(pos cursorPos) int {
+	i, found := slices.BinarySearchFunc(l, pos, comparePos)
+	if found {
+		i++
+	}
+	return i
+}
+
+// firstAt returns the index of the first position with UpdatedAt >= updatedAt
+func (l sortedJobs) firstAt(updatedAt int64) int {
+	return sort.Search(len(l), func(i int) bool { return l[i].updatedAt >= updatedAt })
+}
+
+// walkAscending calls visit on the positions after `after` (all if nil),
+// oldest first, until visit returns false
+func (l sortedJobs) walkAscending(after *cursorPos, visit func(cursorPos) bool) {
+	i := 0
+	if after != nil {
+		i = l.firstAfter(*after)
+	}
+	for ; i < len(l); i++ {
+		if !visit(l[i]) {
+			return
+		}
+	}
+}
+
+// walkDescending is walkAscending newest first. Jobs with the same UpdatedAt
+// still come in ascending ID order, so the list is walked one UpdatedAt group
+// at a time, from the last group down.
+func (l sortedJobs) walkDescending(after *cursorPos, visit func(cursorPos) bool) {
+	end := len(l)
+	if after != nil {
+		// The rest of the cursor's own group, then the older groups
+		groupEnd := sort.Search(len(l), func(i int) bool { return l[i].updatedAt > after.updatedAt })
+		for i := l.firstAfter(*after); i < groupEnd; i++ {
+			if !visit(l[i]) {
+				return
+			}
+		}
+		end = l.firstAt(after.updatedAt)
+	}
+	for end > 0 {
+		start := l.firstAt(l[end-1].updatedAt)
+		for i := start; i < end; i++ {
+			if !visit(l[i]) {
+				return
+			}
+		}
+		end = start
+	}
+}
+
+func encodeCursor(pos cursorPos) string {
+	raw := strconv.FormatInt(pos.updatedAt, 10) + "|" + pos.id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (cursorPos, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return cursorPos{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return cursorPos{}, fmt.Errorf("invalid cursor")
+	}
+	updatedAt, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return cursorPos{}, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return cursorPos{updatedAt: updatedAt, id: id}, nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"slices"
+	"strings"
+)
+
+// ParentStatus summarizes the sub-jobs of a parent job and its aggregation
+type ParentStatus string
+
+const (
+	ParentPending         ParentStatus = "PENDING"          // No sub-job has started
+	ParentRunning         ParentStatus = "RUNNING"          // Sub-jobs are under way
+	ParentPartiallyFailed ParentStatus = "PARTIALLY_FAILED" // Some sub-jobs failed for good
+	ParentFailed          ParentStatus = "FAILED"           // Every sub-job failed, or merging failed
+	ParentCancelled       ParentStatus = "CANCELLED"        // Cancelled before all sub-jobs completed
+	ParentCompleted       ParentStatus = "COMPLETED"        // Every sub-job completed, waiting to be merged
+	ParentMerged          ParentStatus = "MERGED"           // The global model is available
+)
+
+// ParentProgress counts a parent's sub-jobs by status
+type ParentProgress struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Running   int `json:"running"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	Cancelled int `json:"cancelled"`
+}
+
+// ParentJob is a submitted job that was split into one sub-job per worker.
+// Its status, progress and model fields are derived: the state refreshes them
+// whenever a sub-job or the parent's aggregation changes.
+type ParentJob struct {
+	ID          string       `json:"id"`
+	Type        string       `json:"type"`
+	Status      ParentStatus `json:"status"`
+	ChildIDs    []string     `json:"child_ids"` // Sub-jobs in shard order
+	SubmittedAt int64        `json:"submitted_at,omitempty"`
+
+	RetryPolicy *RetryPolicy      `json:"retry_policy,omitempty"` // Passed on to every sub-job
+	Aggregator  *AggregatorConfig `json:"aggregator,omitempty"`   // How the sub-job models are merged
+
+	// Set when the parent is a round of a multi-round TrainingRun
+	TrainingID string `json:"training_id,omitempty"`
+	Round      int    `json:"round,omitempty"`
+
+	Progress ParentProgress `json:"progress"`
+
+	// From the parent's Aggregation, once recorded
+	GlobalModelPath  string `json:"global_model_path,omitempty"`
+	NumModels        int    `json:"num_models,omitempty"`
+	AggregationError string `json:"aggregation_error,omitempty"`
+
+	// Raft log entries that created and last modified the record
+	CreateIndex uint64 `json:"create_index,omitempty"`
+	ModifyIndex uint64 `json:"modify_index,omitempty"`
+}
+
+// Clone returns a deep copy of the parent job
+func (p *ParentJob) Clone() *ParentJob {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.ChildIDs = slices.Clone(p.ChildIDs)
+	c.RetryPolicy = p.RetryPolicy.Clone()
+	c.Aggregator = p.Aggregator.Clone()
+	return &c
+}
+
+// CreateParentAt records a newly submitted parent job as written by the log
+// entry at pos. Its sub-jobs are expected to be applied right after.
+func (s *State) CreateParentAt(pos LogPosition, parent *ParentJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := parent.Clone()
+	stored.CreateIndex = pos.Index
+	s.parents[stored.ID] = stored
+	s.refreshParent(pos, stored.ID)
+}
+
+// GetParent returns a copy of a parent job
+func (s *State) GetParent(id string) (*ParentJob, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.parents[id]
+	return p.Clone(), ok
+}
+
+// GetParentsByStatus returns copies of the parent jobs with the given status
+func (s *State) GetParentsByStatus(status ParentStatus) []*ParentJob {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var parents []*ParentJob
+	for _, p := range s.parents {
+		if p.Status == status {
+			parents = append(parents, p.Clone())
+		}
+	}
+	return parents
+}
+
+// refreshParent recomputes the derived fields of parentID from its sub-jobs
+// and aggregation. A sub-job naming a parent that was never submitted (e.g.
+// one written with SET_JOB) gets a record, so every parent is visible.
+// The caller must hold s.mu.
+func (s *State) refreshParent(pos LogPosition, parentID string) {
+	if parentID == "" {
+		return
+	}
+	prev, ok := s.parents[parentID]
+	if !ok {
+		prev = &ParentJob{ID: parentID, CreateIndex: pos.Index}
+	}
+	parent := prev.Clone()
+	added := false
+	for childID := range s.index.byParent[parentID] {
+		if !slices.Contains(parent.ChildIDs, childID) {
+			parent.ChildIDs = append(parent.ChildIDs, childID)
+			added = true
+		}
+	}
+	if added {
+		slices.SortStableFunc(parent.ChildIDs, func(a, b string) int {
+			if d := s.shardOf(a) - s.shardOf(b); d != 0 {
+				return d
+			}
+			return strings.Compare(a, b)
+		})
+	}
+
+	parent.Progress = ParentProgress{Total: len(parent.ChildIDs)}
+	for _, childID := range parent.ChildIDs {
+		child, ok := s.jobs[childID]
+		if !ok {
+			parent.Progress.Pending++
+			continue
+		}
+		switch child.Status {
+		case StatusRunning:
+			parent.Progress.Running++
+		case StatusCompleted:
+			parent.Progress.Completed++
+		case StatusFailed:
+			parent.Progress.Failed++
+		case StatusCancelled:
+			parent.Progress.Cancelled++
+		default:
+			parent.Progress.Pending++
+		}
+	}
+	if a, ok := s.aggregations[parentID]; ok {
+		parent.GlobalModelPath = a.ModelPath
+		parent.NumModels = a.NumModels
+		parent.AggregationError = a.Error
+	}
+	parent.Status = parentStatus(parent.Progress, s.aggregations[parentID])
+	parent.ModifyIndex = pos.Index
+	s.parents[parentID] = parent
+}
+
+func (s *State) shardOf(jobID string) int {
+	if job, ok := s.jobs[jobID]; ok {
+		return job.ShardIndex
+	}
+	return 0
+}
+
+// parentStatus derives a parent's status; the aggregation, if recorded, decides
+func parentStatus(p ParentProgress, a *Aggregation) ParentStatus {
+	unfinished := p.Pending + p.Running
+	switch {
+	case a != nil && a.Status == AggregationMerged:
+		return ParentMerged
+	case a != nil:
+		return ParentFailed
+	case p.Total > 0 && p.Failed == p.Total:
+		return ParentFailed
+	case p.Failed > 0:
+		return ParentPartiallyFailed
+	case p.Cancelled > 0 && unfinished == 0:
+		return ParentCancelled
+	case p.Total > 0 && p.Completed == p.Total:
+		return ParentCompleted
+	case p.Pending == p.Total:
+		return ParentPending
+	default:
+		return ParentRunning
+	}
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+)
+
+// DefaultRetryOn is what a RetryPolicy without RetryOn retries: problems that
+// may go away on another attempt or node. A failing script is not retried.
+var DefaultRetryOn = []FailureCategory{FailureInfra, FailureTimeout, FailureOOM}
+
+// MaxBackoffSeconds is the longest delay a policy may ask for (one day), and
+// the cap of policies that set none
+const MaxBackoffSeconds = 24 * 60 * 60
+
+// failureCategories are the categories a RetryPolicy may name
+var failureCategories = []FailureCategory{FailureUserCode, FailureInfra, FailureTimeout, FailureOOM}
+
+// RetryPolicy decides whether and when a failed job runs again
+type RetryPolicy struct {
+	MaxAttempts        int               `json:"max_attempts"`         // Total runs, the first one included
+	BackoffBaseSeconds int64             `json:"backoff_base_seconds"` // Delay before the first retry; doubles with each retry
+	BackoffCapSeconds  int64             `json:"backoff_cap_seconds"`  // Upper bound on the delay (0: MaxBackoffSeconds)
+	RetryOn            []FailureCategory `json:"retry_on,omitempty"`   // Retryable categories (default DefaultRetryOn)
+}
+
+// Clone returns a deep copy of the policy
+func (p *RetryPolicy) Clone() *RetryPolicy {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.RetryOn = slices.Clone(p.RetryOn)
+	return &c
+}
+
+// Validate reports why the policy cannot be used, or nil
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be at least 1, got %d", p.MaxAttempts)
+	}
+	for name, seconds := range map[string]int64{"backoff_base_seconds": p.BackoffBaseSeconds, "backoff_cap_seconds": p.BackoffCapSeconds} {
+		if seconds < 0 || seconds > MaxBackoffSeconds {
+			return fmt.Errorf("%s must be between 0 and %d, got %d", name, MaxBackoffSeconds, seconds)
+		}
+	}
+	if p.BackoffCapSeconds > 0 && p.BackoffCapSeconds < p.BackoffBaseSeconds {
+		return fmt.Errorf("backoff_cap_seconds (%d) must be at least backoff_base_seconds (%d)", p.BackoffCapSeconds, p.BackoffBaseSeconds)
+	}
+	for _, category := range p.RetryOn {
+		if !slices.Contains(failureCategories, category) {
+			return fmt.Errorf("unknown retry_on category %q (want one of %v)", category, failureCategories)
+		}
+	}
+	return nil
+}
+
+// Retryable reports whether a failure of this category may be retried
+func (p *RetryPolicy) Retryable(category FailureCategory) bool {
+	retryOn := p.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = DefaultRetryOn
+	}
+	return slices.Contains(retryOn, category)
+}
+
+// Backoff returns the delay in seconds before the given retry (1 for the first)
+func (p *RetryPolicy) Backoff(retry int) int64 {
+	limit := p.BackoffCapSeconds
+	if limit <= 0 {
+		limit = MaxBackoffSeconds
+	}
+	delay := min(p.BackoffBaseSeconds, limit)
+	for i := 1; i < retry && delay > 0 && delay < limit; i++ {
+		if delay > limit/2 {
+			delay = limit
+		} else {
+			delay *= 2
+		}
+	}
+	return max(delay, 0)
+}
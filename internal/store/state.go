@@ -2,6 +2,8 @@ package store
 
 import (
 	"encoding/json"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -13,8 +15,31 @@ const (
 	StatusRunning   JobStatus = "RUNNING"
 	StatusCompleted JobStatus = "COMPLETED"
 	StatusFailed    JobStatus = "FAILED"
+	StatusCancelled JobStatus = "CANCELLED"
 )
 
+// IsTerminal reports whether a job in this status will never run again
+func (st JobStatus) IsTerminal() bool {
+	return st == StatusCompleted || st == StatusFailed || st == StatusCancelled
+}
+
+// FailureCategory tells what kind of problem made a job fail
+type FailureCategory string
+
+const (
+	FailureUserCode FailureCategory = "user_code" // The training script raised or exited non-zero
+	FailureInfra    FailureCategory = "infra"     // The worker could not run the script, or its node is gone
+	FailureTimeout  FailureCategory = "timeout"   // No heartbeat from the worker within the job timeout
+	FailureOOM      FailureCategory = "oom"       // Out of memory, or killed the way the OOM killer does
+)
+
+// Failure describes why an attempt at a job failed
+type Failure struct {
+	Error           string          `json:"error,omitempty"`
+	ExitCode        int             `json:"exit_code,omitempty"` // -1 if the process was killed by a signal
+	FailureCategory FailureCategory `json:"failure_category,omitempty"`
+}
+
 // Job represents a single ML task
 type Job struct {
 	ID         string    `json:"id"`
@@ -25,76 +50,325 @@ type Job struct {
 	StartedAt  int64     `json:"started_at,omitempty"`  // Unix timestamp when job started
 	UpdatedAt  int64     `json:"updated_at,omitempty"`  // Unix timestamp of last update
 	RetryCount int       `json:"retry_count,omitempty"` // Number of retry attempts
+
+	LastHeartbeat int64 `json:"last_heartbeat,omitempty"` // Unix timestamp of the worker's last heartbeat
+
+	// Why the last attempt failed; cleared when the job is claimed again
+	Failure
+
+	// Retries: RetryPolicy overrides the cluster default, and a retried job
+	// may not be claimed before NotBefore (Unix seconds)
+	RetryPolicy *RetryPolicy `json:"retry_policy,omitempty"`
+	NotBefore   int64        `json:"not_before,omitempty"`
+
+	// How a parent job's models are merged, and whether it trains over several
+	// rounds; set on submission and kept on the ParentJob (TrainingRun)
+	// record, not on sub-jobs
+	Aggregator *AggregatorConfig `json:"aggregator,omitempty"`
+	Training   *TrainingConfig   `json:"training,omitempty"`
+
+	// Multi-round training: the global model a sub-job starts from, and what
+	// its completed run reported
+	InitialModel string  `json:"initial_model,omitempty"`
+	Loss         float64 `json:"loss,omitempty"`
+	Accuracy     float64 `json:"accuracy,omitempty"`
+	NumSamples   int     `json:"num_samples,omitempty"`
+
+	// Dead-letter requeues: how often the job was requeued after failing for
+	// good, and its RetryCount at the last requeue, from which the retry
+	// policy counts attempts afresh
+	Requeues        int `json:"requeues,omitempty"`
+	RequeuedAtRetry int `json:"requeued_at_retry,omitempty"`
+
+	// Revision is bumped on every write, so conditional updates can tell
+	// whether the job changed since it was read. Set by State.Apply.
+	Revision uint64 `json:"revision,omitempty"`
+
+	// Raft log entries that created and last modified the job. Set by State.ApplyAt.
+	CreateIndex uint64 `json:"create_index,omitempty"`
+	CreateTerm  uint64 `json:"create_term,omitempty"`
+	ModifyIndex uint64 `json:"modify_index,omitempty"`
+	ModifyTerm  uint64 `json:"modify_term,omitempty"`
+
+	// Sharding info for sub-jobs created from a parent job
+	ParentID    string `json:"parent_id,omitempty"`
+	ShardIndex  int    `json:"shard_index"`            // Zero-based data shard this sub-job trains on
+	TotalShards int    `json:"total_shards,omitempty"` // Number of shards the parent was split into
 }
 
-// State is the thread-safe "Database"
+// Clone returns a deep copy of the job
+func (j *Job) Clone() *Job {
+	if j == nil {
+		return nil
+	}
+	c := *j
+	c.RetryPolicy = j.RetryPolicy.Clone()
+	c.Aggregator = j.Aggregator.Clone()
+	c.Training = j.Training.Clone()
+	return &c
+}
+
+// Node describes a cluster member and the addresses it can be reached at
+type Node struct {
+	ID       string `json:"id"`
+	RaftAddr string `json:"raft_addr"`
+	HTTPAddr string `json:"http_addr"` // host:port of the node's HTTP API
+}
+
+// State is the thread-safe "Database".
+// Readers always get copies; the only way to change state is through the
+// mutating methods (Apply, SetNode, RemoveNode, CreateParentAt, SetAggregation,
+// SetTrainingAt, Unmarshal, ...), which are reserved
+// for the Raft FSM so that every change corresponds to a committed log entry.
 type State struct {
-	sync.RWMutex // make the jobs map thread-safe
-	Jobs         map[string]*Job
+	mu    sync.RWMutex
+	jobs  map[string]*Job
+	nodes map[string]*Node // Node registry keyed by node ID
+	index *jobIndex        // Secondary indexes for ListJobs
+
+	events       map[string][]JobEvent   // Per-job history keyed by job ID
+	aggregations map[string]*Aggregation // Merged models keyed by parent job ID
+	parents      map[string]*ParentJob   // Parent jobs keyed by ID, see parent.go
+	trainings    map[string]*TrainingRun // Multi-round training runs keyed by ID, see training.go
+
+	// Watch support, see watch.go
+	watchers  map[*Watcher]struct{}
+	history   []Change // Recent changes in commit order, oldest first
+	compacted ChangeID // Changes up to this one are no longer in history
+}
+
+// snapshot is the on-disk layout produced by Marshal
+type snapshot struct {
+	Version int                   `json:"version"`
+	Jobs    map[string]*Job       `json:"jobs"`
+	Nodes   map[string]*Node      `json:"nodes"`
+	Events  map[string][]JobEvent `json:"events,omitempty"` // Job histories, see events.go
+
+	Aggregations map[string]*Aggregation `json:"aggregations,omitempty"` // See aggregation.go
+	Parents      map[string]*ParentJob   `json:"parents,omitempty"`      // See parent.go
+	Trainings    map[string]*TrainingRun `json:"trainings,omitempty"`    // See training.go
 }
 
+const snapshotVersion = 1
+
 func NewState() *State {
 	return &State{
-		Jobs: make(map[string]*Job),
+		jobs:         make(map[string]*Job),
+		nodes:        make(map[string]*Node),
+		index:        newJobIndex(),
+		events:       make(map[string][]JobEvent),
+		aggregations: make(map[string]*Aggregation),
+		parents:      make(map[string]*ParentJob),
+		trainings:    make(map[string]*TrainingRun),
+		watchers:     make(map[*Watcher]struct{}),
 	}
 }
 
-// GetJob reads a job safely
+// GetJob returns a copy of a job; changing it does not change the state
 func (s *State) GetJob(id string) (*Job, bool) {
-	s.RLock()
-	defer s.RUnlock()
-	j, ok := s.Jobs[id]
-	return j, ok
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.jobs[id]
+	return j.Clone(), ok
+}
+
+// LogPosition identifies a Raft log entry
+type LogPosition struct {
+	Index uint64
+	Term  uint64
 }
 
-// Apply sets a job state (Writing to the DB)
+// Apply sets a job state (Writing to the DB) outside of any log entry.
+// See ApplyAt.
 func (s *State) Apply(jobID string, job *Job) {
-	s.Lock()
-	defer s.Unlock()
-	s.Jobs[jobID] = job
+	s.ApplyAt(LogPosition{}, jobID, job)
+}
+
+// ApplyAt sets a job state as written by the log entry at pos.
+// The job is copied, so the caller cannot change the stored value afterwards.
+// The stored copy gets the next revision and is stamped with pos as its last
+// modification (and creation, if the job is new), whatever the caller passed.
+func (s *State) ApplyAt(pos LogPosition, jobID string, job *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := job.Clone()
+	stored.Revision = 1
+	stored.CreateIndex, stored.CreateTerm = pos.Index, pos.Term
+	if prev, ok := s.jobs[jobID]; ok {
+		stored.Revision = prev.Revision + 1
+		stored.CreateIndex, stored.CreateTerm = prev.CreateIndex, prev.CreateTerm
+	}
+	stored.ModifyIndex, stored.ModifyTerm = pos.Index, pos.Term
+	prevParent := s.index.keys[jobID].parent
+	s.jobs[jobID] = stored
+	s.index.update(jobID, stored)
+	s.refreshParent(pos, stored.ParentID)
+	if prevParent != stored.ParentID {
+		s.refreshParent(pos, prevParent)
+	}
+	s.notify(stored)
+}
+
+// GetSubJobs returns the sub-jobs created from parentID, in ID order so that
+// the FSM changes them in the same order on every node
+func (s *State) GetSubJobs(parentID string) []*Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var subJobs []*Job
+	for id := range s.index.byParent[parentID] {
+		if job, ok := s.jobs[id]; ok {
+			subJobs = append(subJobs, job.Clone())
+		}
+	}
+	slices.SortFunc(subJobs, func(a, b *Job) int { return strings.Compare(a.ID, b.ID) })
+	return subJobs
+}
+
+// SetNode registers (or updates) a cluster member
+func (s *State) SetNode(node *Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *node
+	s.nodes[node.ID] = &stored
+}
+
+// RemoveNode drops a departed member from the registry
+func (s *State) RemoveNode(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.nodes, id)
+}
+
+// GetNode reads a node registration safely
+func (s *State) GetNode(id string) (*Node, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n, ok := s.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	c := *n
+	return &c, true
+}
+
+// GetNodeByRaftAddr finds the node registered with the given Raft address
+func (s *State) GetNodeByRaftAddr(raftAddr string) (*Node, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, n := range s.nodes {
+		if n.RaftAddr == raftAddr {
+			c := *n
+			return &c, true
+		}
+	}
+	return nil, false
 }
 
 // Marshal dumps state for snapshots
 func (s *State) Marshal() ([]byte, error) {
-	s.RLock()
-	defer s.RUnlock()
-	return json.Marshal(s.Jobs)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(snapshot{Version: snapshotVersion, Jobs: s.jobs, Nodes: s.nodes, Events: s.events,
+		Aggregations: s.aggregations, Parents: s.parents, Trainings: s.trainings})
 }
 
-// Unmarshal restores state from snapshots
+// Unmarshal restores state from snapshots.
+// Snapshots written before the node registry existed are a bare jobs map.
 func (s *State) Unmarshal(data []byte) error {
-	s.Lock()
-	defer s.Unlock()
-	return json.Unmarshal(data, &s.Jobs)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil || snap.Version == 0 {
+		jobs := make(map[string]*Job)
+		if err := json.Unmarshal(data, &jobs); err != nil {
+			return err
+		}
+		snap = snapshot{Jobs: jobs}
+	}
+	if snap.Jobs == nil {
+		snap.Jobs = make(map[string]*Job)
+	}
+	if snap.Nodes == nil {
+		snap.Nodes = make(map[string]*Node)
+	}
+	if snap.Events == nil {
+		snap.Events = make(map[string][]JobEvent)
+	}
+	if snap.Aggregations == nil {
+		snap.Aggregations = make(map[string]*Aggregation)
+	}
+	if snap.Trainings == nil {
+		snap.Trainings = make(map[string]*TrainingRun)
+	}
+	s.jobs = snap.Jobs
+	s.nodes = snap.Nodes
+	s.events = snap.Events
+	s.aggregations = snap.Aggregations
+	s.parents = snap.Parents
+	s.trainings = snap.Trainings
+	s.index = newJobIndex()
+	for id, job := range s.jobs {
+		s.index.update(id, job)
+	}
+	if s.parents == nil {
+		// Snapshots from before parent records existed: derive them from the sub-jobs
+		s.parents = make(map[string]*ParentJob)
+		for parentID := range s.index.byParent {
+			s.refreshParent(LogPosition{}, parentID)
+		}
+	}
+	s.resetWatches()
+	return nil
 }
 
-// GetAllJobs returns a snapshot of all jobs
+// GetAllJobs returns a snapshot (deep copy) of all jobs
 func (s *State) GetAllJobs() map[string]*Job {
-	s.RLock()
-	defer s.RUnlock()
-	snapshot := make(map[string]*Job, len(s.Jobs))
-	for k, v := range s.Jobs {
-		snapshot[k] = v
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := make(map[string]*Job, len(s.jobs))
+	for k, v := range s.jobs {
+		snapshot[k] = v.Clone()
 	}
 	return snapshot
 }
 
-// GetStuckJobs returns jobs that have been running longer than timeout
+// GetStuckJobs returns running jobs whose worker has not shown a sign of life
+// (start or heartbeat) for longer than timeout
 func (s *State) GetStuckJobs(timeoutSeconds int64) []*Job {
-	s.RLock()
-	defer s.RUnlock()
-	
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
 	var stuck []*Job
 	now := time.Now().Unix()
-	
-	for _, job := range s.Jobs {
+
+	for _, job := range s.jobs {
 		if job.Status == StatusRunning && job.StartedAt > 0 {
-			elapsed := now - job.StartedAt
-			if elapsed > timeoutSeconds {
-				stuck = append(stuck, job)
+			lastSeen := max(job.StartedAt, job.LastHeartbeat)
+			if now-lastSeen > timeoutSeconds {
+				stuck = append(stuck, job.Clone())
 			}
 		}
 	}
-	
+
 	return stuck
 }
+
+// GetOrphanedJobs returns unfinished jobs assigned to workers outside members
+func (s *State) GetOrphanedJobs(members []string) []*Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	isMember := make(map[string]bool, len(members))
+	for _, m := range members {
+		isMember[m] = true
+	}
+
+	var orphaned []*Job
+	for _, job := range s.jobs {
+		if (job.Status == StatusPending || job.Status == StatusRunning) && !isMember[job.WorkerID] {
+			orphaned = append(orphaned, job.Clone())
+		}
+	}
+	return orphaned
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+)
+
+// TrainingStatus is the state of a multi-round training run
+type TrainingStatus string
+
+const (
+	TrainingRunning   TrainingStatus = "RUNNING"
+	TrainingConverged TrainingStatus = "CONVERGED" // Stopped early: a round barely moved the global model
+	TrainingCompleted TrainingStatus = "COMPLETED" // Ran every round
+	TrainingFailed    TrainingStatus = "FAILED"    // A round failed: all its sub-jobs, or its merge
+	TrainingCancelled TrainingStatus = "CANCELLED"
+)
+
+// TrainingConfig asks for round-based training: each round trains every
+// shard starting from the previous round's global model and merges the results
+type TrainingConfig struct {
+	Rounds int `json:"rounds"` // Upper bound on the number of rounds
+	// Stop once a round moves the global model by less than this (L2 norm of
+	// the difference); 0 runs every round
+	ConvergenceThreshold float64 `json:"convergence_threshold,omitempty"`
+}
+
+// Clone returns a copy of the config
+func (c *TrainingConfig) Clone() *TrainingConfig {
+	if c == nil {
+		return nil
+	}
+	copied := *c
+	return &copied
+}
+
+// Validate reports why the config cannot be run, or nil
+func (c *TrainingConfig) Validate() error {
+	if c.Rounds < 1 {
+		return fmt.Errorf("rounds must be at least 1, got %d", c.Rounds)
+	}
+	if c.ConvergenceThreshold < 0 {
+		return fmt.Errorf("convergence_threshold must not be negative, got %g", c.ConvergenceThreshold)
+	}
+	return nil
+}
+
+// Round is one round of a training run, with its metrics once it finished
+type Round struct {
+	Round        int          `json:"round"`                   // 1-based
+	ParentID     string       `json:"parent_id"`               // The round's parent job
+	InitialModel string       `json:"initial_model,omitempty"` // Global model the round started from (none for round 1)
+	Status       ParentStatus `json:"status"`                  // RUNNING, then MERGED, FAILED or CANCELLED
+	StartedAt    int64        `json:"started_at,omitempty"`
+	FinishedAt   int64        `json:"finished_at,omitempty"`
+
+	// Metrics, set when the round finished
+	ModelPath  string  `json:"model_path,omitempty"` // The round's global model
+	NumModels  int     `json:"num_models,omitempty"`
+	NumSamples int     `json:"num_samples,omitempty"`
+	Loss       float64 `json:"loss,omitempty"`        // Training loss, weighted by samples across shards
+	Accuracy   float64 `json:"accuracy,omitempty"`    // Training accuracy (%), weighted the same way
+	UpdateNorm float64 `json:"update_norm,omitempty"` // L2 distance from InitialModel to ModelPath
+	Error      string  `json:"error,omitempty"`       // Why a FAILED round failed
+}
+
+// TrainingRun is a submitted multi-round training job. Each round is a
+// parent job of its own, split into one sub-job per worker.
+type TrainingRun struct {
+	ID          string            `json:"id"`
+	Type        string            `json:"type"`
+	Status      TrainingStatus    `json:"status"`
+	Config      TrainingConfig    `json:"config"`
+	RetryPolicy *RetryPolicy      `json:"retry_policy,omitempty"` // Passed on to every round
+	Aggregator  *AggregatorConfig `json:"aggregator,omitempty"`   // Passed on to every round
+
+	Rounds          []Round `json:"rounds"`                      // Started rounds, in order
+	GlobalModelPath string  `json:"global_model_path,omitempty"` // Latest merged global model
+
+	Error string `json:"error,omitempty"` // Why a FAILED run failed, if not because of a round
+
+	SubmittedAt int64 `json:"submitted_at,omitempty"`
+	FinishedAt  int64 `json:"finished_at,omitempty"`
+
+	// Raft log entries that created and last modified the record
+	CreateIndex uint64 `json:"create_index,omitempty"`
+	ModifyIndex uint64 `json:"modify_index,omitempty"`
+}
+
+// RoundParentID is the ID of the parent job running round of training run id
+func RoundParentID(id string, round int) string {
+	return fmt.Sprintf("%s-round-%d", id, round)
+}
+
+// CurrentRound returns the latest started round, or nil if there is none
+func (t *TrainingRun) CurrentRound() *Round {
+	if len(t.Rounds) == 0 {
+		return nil
+	}
+	return &t.Rounds[len(t.Rounds)-1]
+}
+
+// Clone returns a deep copy of the run
+func (t *TrainingRun) Clone() *TrainingRun {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	c.RetryPolicy = t.RetryPolicy.Clone()
+	c.Aggregator = t.Aggregator.Clone()
+	c.Rounds = slices.Clone(t.Rounds)
+	return &c
+}
+
+// SetTrainingAt stores run as written by the log entry at pos
+func (s *State) SetTrainingAt(pos LogPosition, run *TrainingRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := run.Clone()
+	stored.CreateIndex = pos.Index
+	if prev, ok := s.trainings[run.ID]; ok {
+		stored.CreateIndex = prev.CreateIndex
+	}
+	stored.ModifyIndex = pos.Index
+	s.trainings[run.ID] = stored
+}
+
+// GetTraining returns a copy of a training run
+func (s *State) GetTraining(id string) (*TrainingRun, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.trainings[id]
+	return t.Clone(), ok
+}
+
+// GetTrainingsByStatus returns copies of the training runs with the given status
+func (s *State) GetTrainingsByStatus(status TrainingStatus) []*TrainingRun {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var runs []*TrainingRun
+	for _, t := range s.trainings {
+		if t.Status == status {
+			runs = append(runs, t.Clone())
+		}
+	}
+	return runs
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// WatchHistorySize is how many recent job changes State keeps, so watchers
+// can start from an index slightly in the past
+const WatchHistorySize = 1024
+
+var (
+	// ErrCompacted is returned by Watch when changes after the requested index
+	// are no longer retained
+	ErrCompacted = errors.New("watch index is older than the retained history")
+	// ErrWatchLagged ends a watch whose consumer did not keep up with changes
+	ErrWatchLagged = errors.New("watcher fell behind")
+	// ErrStateRestored ends every watch when the state is replaced by a snapshot
+	ErrStateRestored = errors.New("state was restored from a snapshot")
+)
+
+// ChangeID identifies one job change: the Raft index of the entry that made
+// it and its position among that entry's changes, since a single entry (e.g.
+// submitting or cancelling a parent job) can change several jobs. Every node
+// applies the same entries in the same way, so the IDs are the same on all of
+// them and a client can resume its watch on any node.
+type ChangeID struct {
+	Index uint64
+	Seq   int
+}
+
+// String formats the ID as "index.seq"
+func (c ChangeID) String() string {
+	return fmt.Sprintf("%d.%d", c.Index, c.Seq)
+}
+
+// Before reports whether c was committed before o
+func (c ChangeID) Before(o ChangeID) bool {
+	return c.Index < o.Index || (c.Index == o.Index && c.Seq < o.Seq)
+}
+
+// ParseChangeID parses "index.seq". A bare "index" stands for the last change
+// of that entry, so watching from it skips the whole entry.
+func ParseChangeID(s string) (ChangeID, error) {
+	index, seq, hasSeq := strings.Cut(s, ".")
+	id := ChangeID{Seq: math.MaxInt}
+	var err error
+	if id.Index, err = strconv.ParseUint(index, 10, 64); err != nil {
+		return ChangeID{}, fmt.Errorf("invalid change id %q", s)
+	}
+	if hasSeq {
+		if id.Seq, err = strconv.Atoi(seq); err != nil || id.Seq < 0 {
+			return ChangeID{}, fmt.Errorf("invalid change id %q", s)
+		}
+	}
+	return id, nil
+}
+
+// Change is a committed change to one job
+type Change struct {
+	ID  ChangeID
+	Job *Job // The job as of the change
+}
+
+// WatchFilter selects the jobs a watcher is interested in.
+// The zero value matches every job.
+type WatchFilter struct {
+	JobID    string // Only this job
+	ParentID string // Only sub-jobs of this parent
+}
+
+func (f WatchFilter) matches(job *Job) bool {
+	if f.JobID != "" && job.ID != f.JobID {
+		return false
+	}
+	if f.ParentID != "" && job.ParentID != f.ParentID {
+		return false
+	}
+	return true
+}
+
+// Watcher delivers every change to the jobs it watches, in commit order, with
+// a copy of the job. C is closed when the watch ends; Err then tells why (nil
+// after Close).
+type Watcher struct {
+	C <-chan Change
+
+	ch     chan Change
+	filter WatchFilter
+	state  *State
+	once   sync.Once
+	err    error
+}
+
+// Err returns why the watch ended. Only valid once C is closed.
+func (w *Watcher) Err() error {
+	return w.err
+}
+
+// Close stops the watch and closes C
+func (w *Watcher) Close() {
+	w.state.mu.Lock()
+	defer w.state.mu.Unlock()
+	delete(w.state.watchers, w)
+	w.end(nil)
+}
+
+// end closes the channel; callers hold state.mu
+func (w *Watcher) end(err error) {
+	w.once.Do(func() {
+		w.err = err
+		close(w.ch)
+	})
+}
+
+// Watch subscribes to job changes matching filter.
+// With a non-zero after, the retained changes committed after it are
+// replayed first; if some of them are no longer retained, Watch fails with
+// ErrCompacted. buffer is how many undelivered changes may queue up before
+// the watch ends with ErrWatchLagged, so a slow consumer can never stall the FSM.
+func (s *State) Watch(filter WatchFilter, after ChangeID, buffer int) (*Watcher, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replay []Change
+	if after != (ChangeID{}) {
+		if after.Before(s.compacted) {
+			return nil, ErrCompacted
+		}
+		for _, c := range s.history {
+			if after.Before(c.ID) && filter.matches(c.Job) {
+				replay = append(replay, c)
+			}
+		}
+	}
+
+	ch := make(chan Change, len(replay)+max(buffer, 1))
+	for _, c := range replay {
+		ch <- Change{ID: c.ID, Job: c.Job.Clone()}
+	}
+	w := &Watcher{C: ch, ch: ch, filter: filter, state: s}
+	s.watchers[w] = struct{}{}
+	return w, nil
+}
+
+// notify records a stored job change and hands it to the matching watchers.
+// Callers hold s.mu.
+func (s *State) notify(job *Job) {
+	id := ChangeID{Index: job.ModifyIndex}
+	if last := len(s.history) - 1; last >= 0 && s.history[last].ID.Index == id.Index {
+		id.Seq = s.history[last].ID.Seq + 1
+	}
+	s.history = append(s.history, Change{ID: id, Job: job})
+	if len(s.history) > WatchHistorySize {
+		s.compacted = s.history[0].ID
+		copy(s.history, s.history[1:])
+		s.history = s.history[:len(s.history)-1]
+	}
+
+	for w := range s.watchers {
+		if !w.filter.matches(job) {
+			continue
+		}
+		select {
+		case w.ch <- Change{ID: id, Job: job.Clone()}:
+		default:
+			delete(s.watchers, w)
+			w.end(ErrWatchLagged)
+		}
+	}
+}
+
+// resetWatches drops the history and ends every watch after a restore.
+// Callers hold s.mu.
+func (s *State) resetWatches() {
+	// Every change up to the snapshot is gone, including the rest of its last entry
+	s.history = nil
+	s.compacted = ChangeID{}
+	for _, job := range s.jobs {
+		s.compacted.Index = max(s.compacted.Index, job.ModifyIndex)
+	}
+	s.compacted.Seq = math.MaxInt
+	for w := range s.watchers {
+		delete(s.watchers, w)
+		w.end(ErrStateRestored)
+	}
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// ErrUnknownWorker is returned when a requeue names a worker outside the cluster
+var ErrUnknownWorker = errors.New("worker is not a cluster member")
+
+// DefaultRequeueReason is recorded in the history of a requeued job when the admin gave none
+const DefaultRequeueReason = "requeued from dead letter"
+
+// RequeueDeadLetter proposes a REQUEUE_JOB that puts a FAILED job back to
+// PENDING with a fresh set of attempts. It runs on target, or if that is empty
+// on the worker placement chooses. The requeue only applies if the job is
+// unchanged since it was read.
+func RequeueDeadLetter(rNode *consensus.RaftNode, job *store.Job, target, reason string, placement *Placement) (*store.Job, error) {
+	if target == "" {
+		target = placement.Choose(job)
+	} else if !slices.Contains(placement.Workers, target) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownWorker, target)
+	}
+	if reason == "" {
+		reason = DefaultRequeueReason
+	}
+
+	return rNode.RequeueJob(consensus.LogEvent{
+		JobID:        job.ID,
+		NextWorker:   target,
+		Reason:       reason,
+		Precondition: &consensus.Precondition{Revision: job.Revision},
+	}, 5*time.Second)
+}
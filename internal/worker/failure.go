@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// RunError is returned by RunPythonScript when a training run fails
+type RunError struct {
+	Message  string
+	ExitCode int            // -1 if the process was killed by a signal, 0 if it never ran or exited cleanly
+	Signal   syscall.Signal // The signal that killed the process, if ExitCode is -1
+	Category store.FailureCategory
+}
+
+func (e *RunError) Error() string {
+	if e.ExitCode != 0 {
+		return fmt.Sprintf("%s (%s, exit code %d)", e.Message, e.Category, e.ExitCode)
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.Category)
+}
+
+// newRunError builds the RunError for a finished python process.
+// waitErr is what cmd.Wait returned; result is the parsed last output line, if any.
+func newRunError(waitErr error, result *PythonResult) *RunError {
+	runErr := &RunError{}
+	var exitErr *exec.ExitError
+	if errors.As(waitErr, &exitErr) {
+		runErr.ExitCode = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			runErr.Signal = status.Signal()
+		}
+	}
+
+	var errorType string
+	switch {
+	case result != nil && result.Error != "":
+		// The script caught the exception and reported it
+		runErr.Message = result.Error
+		errorType = result.ErrorType
+	case waitErr != nil:
+		runErr.Message = "python script crashed: " + waitErr.Error()
+	default:
+		runErr.Message = "python script reported failure without an error"
+	}
+	runErr.Category = ClassifyFailure(runErr.ExitCode, runErr.Signal, errorType, runErr.Message)
+	return runErr
+}
+
+// ClassifyFailure guesses the failure category of a python run from its exit
+// code or the signal that killed it and, if the script reported one, its
+// exception type and message.
+func ClassifyFailure(exitCode int, signal syscall.Signal, errorType, message string) store.FailureCategory {
+	lower := strings.ToLower(message)
+	switch {
+	case errorType == "MemoryError" || errorType == "OutOfMemoryError" || strings.Contains(lower, "out of memory"):
+		return store.FailureOOM
+	case signal == syscall.SIGKILL || exitCode == 137:
+		// SIGKILL without a report from the script: the kernel's OOM killer is the usual culprit
+		return store.FailureOOM
+	case signal == syscall.SIGTERM || signal == syscall.SIGINT || signal == syscall.SIGHUP:
+		// Stopped from outside, e.g. the node shutting down
+		return store.FailureInfra
+	default:
+		// Including crashes such as SIGSEGV or SIGABRT in the script or its native libraries
+		return store.FailureUserCode
+	}
+}
@@ -1,33 +1,46 @@
 package worker
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net"
 
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/api"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
 	// ModelChunkSize is the size of each model chunk in bytes (1MB)
 	ModelChunkSize = 1024 * 1024
+	// WatchBuffer is how many job changes may queue up for a slow watcher
+	WatchBuffer = 256
 )
 
 // MLWorkerServer implements api.MLWorkerService
 type MLWorkerServer struct {
 	api.UnimplementedMLWorkerServiceServer
-	state *store.State
-	port  int
+	state     *store.State
+	port      int
+	chunkSize int
 }
 
-// NewMLWorkerServer creates a new gRPC server for ML operations
-func NewMLWorkerServer(state *store.State, port int) *MLWorkerServer {
+// NewMLWorkerServer creates a new gRPC server for ML operations.
+// A chunkSize of 0 uses ModelChunkSize.
+func NewMLWorkerServer(state *store.State, port int, chunkSize int) *MLWorkerServer {
+	if chunkSize <= 0 {
+		chunkSize = ModelChunkSize
+	}
 	return &MLWorkerServer{
-		state: state,
-		port:  port,
+		state:     state,
+		port:      port,
+		chunkSize: chunkSize,
 	}
 }
 
@@ -54,19 +67,24 @@ func (s *MLWorkerServer) GetModel(req *api.ModelRequest, stream grpc.ServerStrea
 	// 1. Load the model from disk or memory
 	// 2. Split it into chunks
 	// 3. Stream chunks to the worker
-	// For now, return a simple placeholder response
+	// For now, stream a placeholder payload
+	model := []byte("placeholder model data")
+
+	for chunkID := 0; chunkID*s.chunkSize < len(model); chunkID++ {
+		start := chunkID * s.chunkSize
+		end := min(start+s.chunkSize, len(model))
+		chunk := &api.ModelChunk{
+			ChunkId: int32(chunkID),
+			Data:    model[start:end],
+		}
 
-	chunk := &api.ModelChunk{
-		ChunkId: 0,
-		Data:    []byte("placeholder model data"),
-	}
+		if err := stream.Send(chunk); err != nil {
+			log.Printf("❌ Failed to send model chunk: %v", err)
+			return err
+		}
 
-	if err := stream.Send(chunk); err != nil {
-		log.Printf("❌ Failed to send model chunk: %v", err)
-		return err
+		log.Printf("✓ Model chunk sent (ChunkID: %d)", chunk.ChunkId)
 	}
-
-	log.Printf("✓ Model chunk sent (ChunkID: %d)", chunk.ChunkId)
 	return nil
 }
 
@@ -112,3 +130,92 @@ func (s *MLWorkerServer) SendGradients(stream grpc.ClientStreamingServer[api.Gra
 	log.Printf("✓ Acknowledgment sent for job %s", jobID)
 	return nil
 }
+
+// WatchJobs streams committed job changes from this node's replicated state.
+// A client resumes a dropped watch with the id of the last change it got.
+func (s *MLWorkerServer) WatchJobs(req *api.WatchRequest, stream grpc.ServerStreamingServer[api.JobChange]) error {
+	var after store.ChangeID
+	if req.FromIndex > 0 {
+		// Every change of that entry was seen
+		after = store.ChangeID{Index: req.FromIndex, Seq: math.MaxInt}
+	}
+	if req.After != "" {
+		var err error
+		if after, err = store.ParseChangeID(req.After); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	filter := store.WatchFilter{JobID: req.JobId, ParentID: req.ParentId}
+	watcher, err := s.state.Watch(filter, after, WatchBuffer)
+	if err != nil {
+		return watchStatus(err)
+	}
+	defer watcher.Close()
+
+	// Headers tell the client the watch is registered: no change after this is missed
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case change, ok := <-watcher.C:
+			if !ok {
+				return watchStatus(watcher.Err())
+			}
+			if err := stream.Send(&api.JobChange{Id: change.ID.String(), Job: JobToProto(change.Job)}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchStatus maps the reason a watch ended to a gRPC status
+func watchStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, store.ErrCompacted):
+		return status.Error(codes.OutOfRange, err.Error())
+	case errors.Is(err, store.ErrWatchLagged):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	default:
+		return status.Error(codes.Aborted, err.Error())
+	}
+}
+
+// JobToProto converts a store.Job to its wire form
+func JobToProto(job *store.Job) *api.Job {
+	return &api.Job{
+		Id:              job.ID,
+		Type:            job.Type,
+		Status:          string(job.Status),
+		WorkerId:        job.WorkerID,
+		ResultUrl:       job.ResultURL,
+		StartedAt:       job.StartedAt,
+		UpdatedAt:       job.UpdatedAt,
+		RetryCount:      int32(job.RetryCount),
+		LastHeartbeat:   job.LastHeartbeat,
+		ParentId:        job.ParentID,
+		ShardIndex:      int32(job.ShardIndex),
+		TotalShards:     int32(job.TotalShards),
+		Revision:        job.Revision,
+		CreateIndex:     job.CreateIndex,
+		CreateTerm:      job.CreateTerm,
+		ModifyIndex:     job.ModifyIndex,
+		ModifyTerm:      job.ModifyTerm,
+		Error:           job.Error,
+		ExitCode:        int32(job.ExitCode),
+		FailureCategory: string(job.FailureCategory),
+		NotBefore:       job.NotBefore,
+		Requeues:        int32(job.Requeues),
+		RequeuedAtRetry: int32(job.RequeuedAtRetry),
+		InitialModel:    job.InitialModel,
+		Loss:            job.Loss,
+		Accuracy:        job.Accuracy,
+		NumSamples:      int32(job.NumSamples),
+	}
+}
@@ -1,6 +1,8 @@
 package worker
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"time"
 
@@ -9,26 +11,32 @@ import (
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 )
 
-const (
-	// JobTimeoutSeconds - jobs running longer than this are considered stuck
-	JobTimeoutSeconds = 15 // 15 seconds for testing (increase to 120+ for production)
-	// MaxRetries - maximum retry attempts before marking as permanently failed
-	MaxRetries = 2
-	// HealthCheckInterval - how often to check for stuck jobs
-	HealthCheckInterval = 5 * time.Second
-)
-
-// RunHealthMonitor periodically checks for stuck jobs and handles them
-func RunHealthMonitor(state *store.State, rNode *consensus.RaftNode, clusterSize int) {
-	log.Printf("🏥 HEALTH MONITOR STARTED (timeout: %ds, check interval: %v)", JobTimeoutSeconds, HealthCheckInterval)
+// Settings tunes the worker loop and the health monitor; config.Default()
+// has the defaults
+type Settings struct {
+	JobTimeout          time.Duration
+	MaxRetries          int
+	HealthCheckInterval time.Duration
+	HeartbeatInterval   time.Duration
+	RetryBackoffBase    time.Duration
+	RetryBackoffCap     time.Duration
+	DataDir             string // Where trained models are written
+}
 
-	// Get available worker IDs (assumes node-1, node-2, node-3)
-	workerIDs := []string{}
-	for i := 1; i <= clusterSize; i++ {
-		workerIDs = append(workerIDs, consensus.NodeIDFromIndex(i))
+// RetryPolicy is the policy for jobs that were submitted without one
+func (s Settings) RetryPolicy() *store.RetryPolicy {
+	return &store.RetryPolicy{
+		MaxAttempts:        s.MaxRetries + 1,
+		BackoffBaseSeconds: int64(s.RetryBackoffBase / time.Second),
+		BackoffCapSeconds:  int64(s.RetryBackoffCap / time.Second),
 	}
+}
 
-	ticker := time.NewTicker(HealthCheckInterval)
+// RunHealthMonitor periodically checks for stuck jobs and handles them
+func RunHealthMonitor(state *store.State, rNode *consensus.RaftNode, settings Settings) {
+	log.Printf("🏥 HEALTH MONITOR STARTED (timeout: %v, check interval: %v)", settings.JobTimeout, settings.HealthCheckInterval)
+
+	ticker := time.NewTicker(settings.HealthCheckInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -37,7 +45,20 @@ func RunHealthMonitor(state *store.State, rNode *consensus.RaftNode, clusterSize
 			continue
 		}
 
-		stuckJobs := state.GetStuckJobs(JobTimeoutSeconds)
+		// Reassign among the current members (including worker-only nodes), not the ones at startup,
+		// preferring reachable, lightly loaded ones
+		placement, err := CurrentPlacement(state, rNode)
+		if err != nil {
+			log.Printf("⚠️ Failed to read cluster configuration: %v", err)
+			continue
+		}
+
+		// Jobs owned by nodes that left the cluster would never be picked up
+		for _, job := range state.GetOrphanedJobs(placement.Workers) {
+			ReassignOrphanedJob(rNode, job, placement)
+		}
+
+		stuckJobs := state.GetStuckJobs(int64(settings.JobTimeout / time.Second))
 		if len(stuckJobs) == 0 {
 			continue
 		}
@@ -45,76 +66,91 @@ func RunHealthMonitor(state *store.State, rNode *consensus.RaftNode, clusterSize
 		log.Printf("🚨 Found %d stuck job(s)", len(stuckJobs))
 
 		for _, job := range stuckJobs {
-			HandleStuckJob(rNode, job, workerIDs)
+			HandleStuckJob(rNode, job, placement, settings)
 		}
 	}
 }
 
-// HandleStuckJob decides whether to retry or mark as failed
-func HandleStuckJob(rNode *consensus.RaftNode, job *store.Job, workerIDs []string) {
-	log.Printf("⚠️ Handling stuck job: %s (worker: %s, retries: %d, running for: %ds)",
-		job.ID, job.WorkerID, job.RetryCount, time.Now().Unix()-job.StartedAt)
-
-	if job.RetryCount >= MaxRetries {
-		// Exceeded retry limit - mark as permanently failed
-		log.Printf("❌ Job %s exceeded retry limit (%d). Marking as FAILED.", job.ID, MaxRetries)
-		job.Status = store.StatusFailed
-		job.UpdatedAt = time.Now().Unix()
-		applyJobUpdate(rNode, job)
-		return
-	}
-
-	// Attempt reassignment to a different worker
-	newWorkerID := findAlternativeWorker(job.WorkerID, workerIDs)
+// ReassignOrphanedJob moves an unfinished job off a node that left the cluster.
+// The departure is not the job's fault, so it does not count as a retry.
+func ReassignOrphanedJob(rNode *consensus.RaftNode, job *store.Job, placement *Placement) {
+	newWorkerID := placement.Choose(job)
 	if newWorkerID == "" {
-		// No alternative found - mark as failed
-		log.Printf("❌ No alternative worker for job %s. Marking as FAILED.", job.ID)
-		job.Status = store.StatusFailed
-		job.UpdatedAt = time.Now().Unix()
-		applyJobUpdate(rNode, job)
+		log.Printf("⚠️ No member left to take over job %s from %s", job.ID, job.WorkerID)
 		return
 	}
 
-	// Reassign to a different worker
-	log.Printf("🔄 Reassigning job %s: %s -> %s (retry %d/%d)",
-		job.ID, job.WorkerID, newWorkerID, job.RetryCount+1, MaxRetries)
-
-	job.Status = store.StatusPending
-	job.WorkerID = newWorkerID
-	job.RetryCount++
-	job.StartedAt = 0 // Reset start time
-	job.UpdatedAt = time.Now().Unix()
-
-	applyJobUpdate(rNode, job)
+	log.Printf("👋 Reassigning job %s from departed node %s -> %s", job.ID, job.WorkerID, newWorkerID)
+
+	updated := *job
+	updated.Status = store.StatusPending
+	updated.WorkerID = newWorkerID
+	updated.StartedAt = 0
+	updated.LastHeartbeat = 0
+	updated.UpdatedAt = time.Now().Unix()
+	updated.Error = fmt.Sprintf("node %s left the cluster", job.WorkerID)
+	updated.ExitCode = 0
+	updated.FailureCategory = store.FailureInfra
+	applyJobUpdate(rNode, &updated, "", updated.Error)
 }
 
-// findAlternativeWorker selects a different worker (simple round-robin for now)
-func findAlternativeWorker(currentWorker string, allWorkers []string) string {
-	// Find next worker in the list
-	for i, w := range allWorkers {
-		if w == currentWorker {
-			// Return next worker (wrap around)
-			nextIdx := (i + 1) % len(allWorkers)
-			return allWorkers[nextIdx]
-		}
+// HandleStuckJob reports a job whose worker went silent as a timed-out attempt.
+// The job's retry policy decides whether it is retried elsewhere or marked FAILED.
+func HandleStuckJob(rNode *consensus.RaftNode, job *store.Job, placement *Placement, settings Settings) {
+	silentFor := time.Now().Unix() - max(job.StartedAt, job.LastHeartbeat)
+	log.Printf("⚠️ Handling stuck job: %s (worker: %s, retries: %d, silent for: %ds)",
+		job.ID, job.WorkerID, job.RetryCount, silentFor)
+
+	failure := store.Failure{
+		Error:           fmt.Sprintf("no heartbeat from %s for %ds", job.WorkerID, silentFor),
+		FailureCategory: store.FailureTimeout,
 	}
-	// Fallback: return first worker
-	if len(allWorkers) > 0 {
-		return allWorkers[0]
+	// Only if the job is still the silent attempt we looked at
+	pre := &consensus.Precondition{Status: store.StatusRunning, WorkerID: job.WorkerID, Revision: job.Revision}
+	updated, err := ProposeFailure(rNode, job, failure, store.EventHeartbeatLost, pre, placement, settings)
+	switch {
+	case errors.Is(err, consensus.ErrPreconditionFailed):
+		log.Printf("⏭️ Job %s changed while handling it, leaving it alone: %v", job.ID, err)
+	case err != nil:
+		log.Printf("❌ Failed to apply job update via RAFT: %v", err)
+	case updated.Status == store.StatusFailed:
+		log.Printf("❌ Job %s will not be retried. Marked as FAILED.", job.ID)
+	default:
+		log.Printf("🔄 Retrying job %s on %s after %ds (retry %d)",
+			job.ID, updated.WorkerID, updated.NotBefore-updated.UpdatedAt, updated.RetryCount)
 	}
-	return ""
 }
 
-// applyJobUpdate sends a job update through RAFT
-func applyJobUpdate(rNode *consensus.RaftNode, job *store.Job) {
-	event := consensus.LogEvent{
-		Type: consensus.CmdSetJob,
-		Job:  job,
-	}
+// ProposeFailure reports a failed attempt at job through Raft (FAIL_JOB).
+// The FSM applies the job's retry policy, or settings' default, so worker
+// reports and the health monitor retry jobs the same way. A retry goes to the
+// worker placement chooses.
+func ProposeFailure(rNode *consensus.RaftNode, job *store.Job, failure store.Failure, cause store.JobEventType,
+	pre *consensus.Precondition, placement *Placement, settings Settings) (*store.Job, error) {
+	return rNode.FailJob(consensus.LogEvent{
+		JobID:        job.ID,
+		Failure:      &failure,
+		Cause:        cause,
+		Precondition: pre,
+		RetryPolicy:  settings.RetryPolicy(),
+		NextWorker:   placement.Choose(job),
+	}, 5*time.Second)
+}
 
-	data := consensus.MustMarshalEvent(event)
-	future := rNode.Raft.Apply(data, 5*time.Second)
-	if err := future.Error(); err != nil {
+// applyJobUpdate sends a job update through RAFT.
+// job still carries the revision it was read at, and the update only applies
+// if nothing (e.g. a worker's late report) changed the job since.
+// cause and reason are recorded in the job's history.
+func applyJobUpdate(rNode *consensus.RaftNode, job *store.Job, cause store.JobEventType, reason string) {
+	_, err := rNode.UpdateJob(consensus.LogEvent{
+		Job:          job,
+		Precondition: &consensus.Precondition{Revision: job.Revision},
+		Cause:        cause,
+		Reason:       reason,
+	}, 5*time.Second)
+	if errors.Is(err, consensus.ErrPreconditionFailed) {
+		log.Printf("⏭️ Job %s changed while handling it, leaving it alone: %v", job.ID, err)
+	} else if err != nil {
 		log.Printf("❌ Failed to apply job update via RAFT: %v", err)
 	}
 }
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// LeaderRetryAttempts - how many times a report is retried across leader changes
+	LeaderRetryAttempts = 5
+	// LeaderRetryBackoff - initial wait between attempts (doubles each time)
+	LeaderRetryBackoff = 500 * time.Millisecond
+)
+
+// LeaderResolver finds the HTTP address of the current Raft leader.
+// *consensus.RaftNode implements it using the replicated node registry.
+type LeaderResolver interface {
+	LeaderHTTPAddr() (string, error)
+}
+
+// LeaderResolverFunc adapts a plain function to LeaderResolver
+type LeaderResolverFunc func() (string, error)
+
+func (f LeaderResolverFunc) LeaderHTTPAddr() (string, error) { return f() }
+
+// StaticLeader always resolves to the same address (useful for tests and single-node setups)
+func StaticLeader(addr string) LeaderResolver {
+	return LeaderResolverFunc(func() (string, error) { return addr, nil })
+}
+
+// LeaderClient posts worker reports to the current leader.
+// The leader is re-resolved before every attempt, so a report that fails
+// because of an election is retried against the new leader.
+type LeaderClient struct {
+	resolver LeaderResolver
+	attempts int
+	backoff  time.Duration
+	http     *http.Client
+}
+
+// NewLeaderClient creates a client that resolves the leader through resolver
+func NewLeaderClient(resolver LeaderResolver) *LeaderClient {
+	return &LeaderClient{
+		resolver: resolver,
+		attempts: LeaderRetryAttempts,
+		backoff:  LeaderRetryBackoff,
+		http:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Post sends a JSON payload to path on the leader, retrying on leader changes
+func (c *LeaderClient) Post(path string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	wait := c.backoff
+	for attempt := 1; attempt <= c.attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		addr, err := c.resolver.LeaderHTTPAddr()
+		if err != nil {
+			lastErr = fmt.Errorf("resolve leader: %w", err)
+			continue
+		}
+
+		retry, err := c.postOnce(leaderURL(addr)+path, data)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry {
+			return err
+		}
+		log.Printf("⚠️ POST %s to %s failed (attempt %d/%d): %v", path, addr, attempt, c.attempts, err)
+	}
+	return lastErr
+}
+
+// postOnce performs a single POST and reports whether a failure is worth retrying
+func (c *LeaderClient) postOnce(url string, data []byte) (bool, error) {
+	resp, err := c.http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		// Connection refused / timeout: the leader may have died
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return false, nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = &StatusError{Code: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+	switch resp.StatusCode {
+	case http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout, http.StatusInternalServerError:
+		// No leader, leader unreachable, or "not leader" from a stale resolution
+		return true, err
+	}
+	return false, err
+}
+
+// StatusError is returned when the leader answers with a non-200 status
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.Code, e.Message)
+}
+
+// IsConflict reports whether err is the leader rejecting a stale precondition (409)
+func IsConflict(err error) bool {
+	var se *StatusError
+	return errors.As(err, &se) && se.Code == http.StatusConflict
+}
+
+// leaderURL builds a base URL from "host:port", or ":port" meaning this host
+func leaderURL(addr string) string {
+	if strings.HasPrefix(addr, "http://") || strings.HasPrefix(addr, "https://") {
+		return addr
+	}
+	if strings.HasPrefix(addr, ":") {
+		return "http://localhost" + addr
+	}
+	return "http://" + addr
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"slices"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// Placement is the leader's view of the workers when it moves a job elsewhere
+type Placement struct {
+	Workers     []string             // Cluster members, in configuration order
+	Unreachable map[string]time.Time // Members the leader cannot heartbeat, with their last contact
+	Load        map[string]int       // PENDING + RUNNING jobs per worker
+
+	state *store.State
+}
+
+// NewPlacement builds a Placement over workers, reading loads and job
+// histories from state
+func NewPlacement(state *store.State, workers []string, unreachable map[string]time.Time) *Placement {
+	return &Placement{
+		Workers:     workers,
+		Unreachable: unreachable,
+		Load:        state.WorkerLoad(),
+		state:       state,
+	}
+}
+
+// CurrentPlacement is NewPlacement for the current Raft members, as seen by
+// rNode. Liveness is only known on the leader; elsewhere every member counts as reachable.
+func CurrentPlacement(state *store.State, rNode *consensus.RaftNode) (*Placement, error) {
+	workers, err := rNode.Members()
+	if err != nil {
+		return nil, err
+	}
+	return NewPlacement(state, workers, rNode.Unreachable()), nil
+}
+
+// Choose picks the worker job should move to: the least-loaded reachable
+// member that has not failed the job before. The job's current worker counts
+// as having failed it, since a job is only moved when its attempt there went
+// wrong. If every member is unreachable or has failed the job, the best of
+// them is used anyway; ties go to the next member after the current worker,
+// so equal workers take turns. The choice is added to Load, so a caller
+// moving several jobs spreads them. Returns "" if there are no members.
+func (p *Placement) Choose(job *store.Job) string {
+	if len(p.Workers) == 0 {
+		return ""
+	}
+	failed := p.failedWorkers(job)
+	start := slices.Index(p.Workers, job.WorkerID) + 1
+
+	var best candidate
+	for i := range p.Workers {
+		id := p.Workers[(start+i)%len(p.Workers)]
+		_, unreachable := p.Unreachable[id]
+		c := candidate{id: id, unreachable: unreachable, failed: failed[id], load: p.Load[id]}
+		if i == 0 || c.better(best) {
+			best = c
+		}
+	}
+
+	if best.id != job.WorkerID {
+		p.Load[best.id]++
+		if !job.Status.IsTerminal() && p.Load[job.WorkerID] > 0 {
+			p.Load[job.WorkerID]--
+		}
+	}
+	return best.id
+}
+
+type candidate struct {
+	id          string
+	unreachable bool
+	failed      bool
+	load        int
+}
+
+// better orders workers by reachability, then by whether they failed the
+// job, then by load
+func (c candidate) better(than candidate) bool {
+	if c.unreachable != than.unreachable {
+		return !c.unreachable
+	}
+	if c.failed != than.failed {
+		return !c.failed
+	}
+	return c.load < than.load
+}
+
+// failedWorkers returns the workers an attempt at job failed or timed out on,
+// from its history, plus its current worker
+func (p *Placement) failedWorkers(job *store.Job) map[string]bool {
+	failed := map[string]bool{job.WorkerID: true}
+	for _, e := range p.state.GetJobEvents(job.ID) {
+		if e.Type == store.EventFailed || e.Type == store.EventHeartbeatLost {
+			failed[e.WorkerID] = true
+		}
+	}
+	return failed
+}
@@ -2,11 +2,11 @@ package worker
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
 	"strings"
@@ -15,63 +15,113 @@ import (
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 )
 
+// CancelPollInterval - how often a running job is checked for cancellation
+const CancelPollInterval = 1 * time.Second
+
 // Helper struct to match the Python JSON output
 type PythonResult struct {
-	JobID     string  `json:"job_id"`
-	Status    string  `json:"status"`
-	Accuracy  float64 `json:"accuracy"`
-	Loss      float64 `json:"loss"`
-	ModelPath string  `json:"model_path"`
+	JobID      string  `json:"job_id"`
+	Status     string  `json:"status"`
+	Accuracy   float64 `json:"accuracy"`
+	Loss       float64 `json:"loss"`
+	ModelPath  string  `json:"model_path"`
+	NumSamples int     `json:"num_samples,omitempty"` // Training samples in the shard
+	Error      string  `json:"error,omitempty"`       // Set when Status is FAILED
+	ErrorType  string  `json:"error_type,omitempty"`  // Python exception class, e.g. MemoryError
 }
 
-func RunWorker(state *store.State, httpAddr string, nodeID string, clusterSize int) {
-	log.Printf("👷 WORKER STARTED: Node %s (Cluster Size: %d)\n", nodeID, clusterSize)
+// NextRunnableJob returns the oldest PENDING job assigned to nodeID that may
+// start at now, skipping jobs still waiting out a retry backoff (NotBefore).
+// It reads small pages, so the usual case looks at a single job.
+func NextRunnableJob(state *store.State, nodeID string, now int64) *store.Job {
+	q := store.JobQuery{
+		Status:    store.StatusPending,
+		WorkerID:  nodeID,
+		Ascending: true,
+		Limit:     runnablePageSize,
+	}
+	for {
+		pending, next, _ := state.ListJobs(q)
+		for _, job := range pending {
+			if job.NotBefore <= now {
+				return job
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		q.Cursor = next
+	}
+}
+
+// runnablePageSize is how many pending jobs NextRunnableJob reads at a time
+const runnablePageSize = 16
+
+// RunWorker polls the replicated state for jobs assigned to nodeID, runs them,
+// and reports progress to whichever node is currently the leader.
+func RunWorker(state *store.State, leader LeaderResolver, nodeID string, settings Settings) {
+	log.Printf("👷 WORKER STARTED: Node %s\n", nodeID)
+	client := NewLeaderClient(leader)
 
 	for {
 		time.Sleep(2 * time.Second)
 
-		// 1. Find a Pending Job assigned to this worker
-		var jobToRun *store.Job
-		state.RLock()
-		for _, job := range state.Jobs {
-			if job.Status == store.StatusPending && job.WorkerID == nodeID {
-				jobToRun = job
-				break
-			}
+		// 1. Find a Pending Job assigned to this worker (oldest first) whose
+		// retry backoff has passed. Work on a copy: the *Job in state belongs to the FSM.
+		next := NextRunnableJob(state, nodeID, time.Now().Unix())
+		if next == nil {
+			continue
 		}
-		state.RUnlock()
+		jobToRun := *next
 
-		if jobToRun == nil {
+		// 2. Claim it through Raft. Only the worker whose claim commits first
+		// may start; a reassignment in between makes our claim fail.
+		if err := client.Claim(&jobToRun); err != nil {
+			if IsConflict(err) {
+				log.Printf("⏭️ Lost claim on %s: %v", jobToRun.ID, err)
+			} else {
+				log.Printf("⚠️ Failed to claim job %s: %v", jobToRun.ID, err)
+			}
 			continue
 		}
-
-		// 2. Mark job as RUNNING with timestamp
 		jobToRun.Status = store.StatusRunning
-		jobToRun.StartedAt = time.Now().Unix()
-		jobToRun.UpdatedAt = time.Now().Unix()
-		if err := UpdateJobStatus(":8000", jobToRun); err != nil {
-			log.Printf("⚠️ Failed to update job to RUNNING: %v", err)
-		}
 
-		// 3. Run the Job
+		// 3. Run the Job, killing it if someone cancels it meanwhile
 		log.Printf("🚀 Found Pending Job: %s. Starting Python...", jobToRun.ID)
-		result, err := RunPythonScript(jobToRun.ID, nodeID, clusterSize)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := watchCancellation(ctx, state, jobToRun.ID, cancel)
+		go sendHeartbeats(ctx, client, jobToRun.ID, nodeID, settings.HeartbeatInterval)
+		// The shard comes from the job, not the node: a reassigned job keeps its data slice
+		result, err := RunPythonScript(ctx, jobToRun.ID, fmt.Sprintf("%d", jobToRun.ShardIndex), max(jobToRun.TotalShards, 1), jobToRun.InitialModel, settings.DataDir)
+		cancel()
+
+		if <-cancelled {
+			log.Printf("🛑 Job %s was cancelled, python process stopped", jobToRun.ID)
+			continue
+		}
 
 		if err != nil {
 			log.Printf("❌ Job %s failed: %v", jobToRun.ID, err)
 			// Mark as failed
 			jobToRun.Status = store.StatusFailed
 			jobToRun.UpdatedAt = time.Now().Unix()
-			if err := UpdateJobStatus(":8000", jobToRun); err != nil {
+			runErr := &RunError{Message: err.Error(), Category: store.FailureInfra}
+			errors.As(err, &runErr)
+			jobToRun.Error, jobToRun.ExitCode, jobToRun.FailureCategory = runErr.Message, runErr.ExitCode, runErr.Category
+			if err := client.FailJob(&jobToRun); IsConflict(err) {
+				log.Printf("⏭️ Job %s changed hands meanwhile, failure discarded: %v", jobToRun.ID, err)
+			} else if err != nil {
 				log.Printf("⚠️ Failed to update job to FAILED: %v", err)
 			}
 			continue
 		}
 
 		// 4. Report Success to Raft (Close the Loop!)
-		// Always report to leader on port 8000
+		// The client re-resolves the leader if an election happened mid-job
 		log.Printf("📬 Reporting completion for %s to Cluster...", jobToRun.ID)
-		if err := ReportSuccess(":8000", result); err != nil {
+		if err := client.CompleteJob(&jobToRun, result); IsConflict(err) {
+			log.Printf("⏭️ Job %s changed hands meanwhile, result discarded: %v", jobToRun.ID, err)
+		} else if err != nil {
 			log.Printf("❌ Failed to report success: %v", err)
 		} else {
 			log.Printf("✅ Job %s cycle complete.", jobToRun.ID)
@@ -79,15 +129,65 @@ func RunWorker(state *store.State, httpAddr string, nodeID string, clusterSize i
 	}
 }
 
-func RunPythonScript(jobID string, shardIndex string, totalShards int) (*PythonResult, error) {
-	cmd := exec.Command("python3", "ml-code/train.py", jobID,
+// watchCancellation polls the replicated state while a job runs and calls stop
+// as soon as the job is CANCELLED. Once ctx is done, the returned channel
+// delivers whether the job was cancelled.
+func watchCancellation(ctx context.Context, state *store.State, jobID string, stop context.CancelFunc) <-chan bool {
+	cancelled := make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(CancelPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				cancelled <- false
+				return
+			case <-ticker.C:
+				if job, ok := state.GetJob(jobID); ok && job.Status == store.StatusCancelled {
+					stop()
+					cancelled <- true
+					return
+				}
+			}
+		}
+	}()
+	return cancelled
+}
+
+// sendHeartbeats keeps the job's lease alive on the leader until ctx is done
+func sendHeartbeats(ctx context.Context, client *LeaderClient, jobID, nodeID string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := client.Heartbeat(jobID, nodeID); err != nil {
+				log.Printf("⚠️ Heartbeat for %s failed: %v", jobID, err)
+			}
+		}
+	}
+}
+
+// RunPythonScript trains one shard, starting from the global model at
+// initialModel if one is given (multi-round training), and writes its model
+// to outputDir; cancelling ctx kills the python process. A failed run returns
+// a *RunError saying why.
+func RunPythonScript(ctx context.Context, jobID string, shardIndex string, totalShards int, initialModel, outputDir string) (*PythonResult, error) {
+	args := []string{"ml-code/train.py", jobID,
 		"--shard_index", shardIndex,
-		"--total_shards", fmt.Sprintf("%d", totalShards))
+		"--total_shards", fmt.Sprintf("%d", totalShards),
+		"--output_dir", outputDir}
+	if initialModel != "" {
+		args = append(args, "--init_model", initialModel)
+	}
+	cmd := exec.CommandContext(ctx, "python3", args...)
 	cmd.Stderr = os.Stderr
 	stdout, _ := cmd.StdoutPipe()
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start python: %v", err)
+		return nil, &RunError{Message: "failed to start python: " + err.Error(), Category: store.FailureInfra}
 	}
 
 	var lastLine string
@@ -101,14 +201,19 @@ func RunPythonScript(jobID string, shardIndex string, totalShards int) (*PythonR
 		}
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("python script crashed: %v", err)
-	}
+	waitErr := cmd.Wait()
 
-	// Parse the final JSON line
+	// Parse the final JSON line; on failure it may carry the script's error
 	var result PythonResult
-	if err := json.Unmarshal([]byte(lastLine), &result); err != nil {
-		return nil, fmt.Errorf("failed to parse result JSON: %v", err)
+	parseErr := json.Unmarshal([]byte(lastLine), &result)
+	if waitErr != nil || result.Status == "FAILED" {
+		if parseErr != nil {
+			return nil, newRunError(waitErr, nil)
+		}
+		return nil, newRunError(waitErr, &result)
+	}
+	if parseErr != nil {
+		return nil, &RunError{Message: fmt.Sprintf("failed to parse result JSON: %v", parseErr), Category: store.FailureUserCode}
 	}
 
 	return &result, nil
@@ -116,6 +221,16 @@ func RunPythonScript(jobID string, shardIndex string, totalShards int) (*PythonR
 
 // ReportSuccess sends the result back to the Leader via HTTP
 func ReportSuccess(leaderAddr string, result *PythonResult) error {
+	return NewLeaderClient(StaticLeader(leaderAddr)).ReportSuccess(result)
+}
+
+// UpdateJobStatus sends a job status update to the leader
+func UpdateJobStatus(leaderAddr string, job *store.Job) error {
+	return NewLeaderClient(StaticLeader(leaderAddr)).UpdateJobStatus(job)
+}
+
+// ReportSuccess sends the result back to the current leader
+func (c *LeaderClient) ReportSuccess(result *PythonResult) error {
 	// Construct the payload for the API
 	// Note: We are reusing the existing 'Job' struct structure
 	payload := map[string]interface{}{
@@ -123,22 +238,11 @@ func ReportSuccess(leaderAddr string, result *PythonResult) error {
 		"status":     "COMPLETED",
 		"result_url": result.ModelPath,
 	}
-
-	data, _ := json.Marshal(payload)
-	resp, err := http.Post("http://localhost"+leaderAddr+"/update", "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("server returned %d", resp.StatusCode)
-	}
-	return nil
+	return c.Post("/update", payload)
 }
 
-// UpdateJobStatus sends a job status update to the leader
-func UpdateJobStatus(leaderAddr string, job *store.Job) error {
+// UpdateJobStatus sends a job status update to the current leader
+func (c *LeaderClient) UpdateJobStatus(job *store.Job) error {
 	payload := map[string]interface{}{
 		"id":          job.ID,
 		"status":      string(job.Status),
@@ -146,16 +250,59 @@ func UpdateJobStatus(leaderAddr string, job *store.Job) error {
 		"updated_at":  job.UpdatedAt,
 		"retry_count": job.RetryCount,
 	}
+	return c.Post("/update", payload)
+}
+
+// CompleteJob reports a successful run of job, which this worker claimed.
+// It fails with a conflict (see IsConflict) if the job is no longer RUNNING
+// on job.WorkerID, e.g. because it was reassigned while we were training.
+func (c *LeaderClient) CompleteJob(job *store.Job, result *PythonResult) error {
+	payload := map[string]interface{}{
+		"id":                 job.ID,
+		"status":             string(store.StatusCompleted),
+		"result_url":         result.ModelPath,
+		"loss":               result.Loss,
+		"accuracy":           result.Accuracy,
+		"num_samples":        result.NumSamples,
+		"expected_status":    string(store.StatusRunning),
+		"expected_worker_id": job.WorkerID,
+	}
+	return c.Post("/update", payload)
+}
+
+// FailJob reports a failed run of job, with the same precondition as CompleteJob.
+// job's Error, ExitCode and FailureCategory say why; Error also ends up in the job's history.
+func (c *LeaderClient) FailJob(job *store.Job) error {
+	payload := map[string]interface{}{
+		"id":                 job.ID,
+		"status":             string(store.StatusFailed),
+		"updated_at":         job.UpdatedAt,
+		"error":              job.Error,
+		"exit_code":          job.ExitCode,
+		"failure_category":   string(job.FailureCategory),
+		"expected_status":    string(store.StatusRunning),
+		"expected_worker_id": job.WorkerID,
+	}
+	return c.Post("/update", payload)
+}
 
-	data, _ := json.Marshal(payload)
-	resp, err := http.Post("http://localhost"+leaderAddr+"/update", "application/json", bytes.NewBuffer(data))
-	if err != nil {
-		return err
+// Heartbeat tells the current leader that workerID is still running jobID
+func (c *LeaderClient) Heartbeat(jobID, workerID string) error {
+	payload := map[string]interface{}{
+		"id":        jobID,
+		"worker_id": workerID,
 	}
-	defer resp.Body.Close()
+	return c.Post("/heartbeat", payload)
+}
 
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("server returned %d", resp.StatusCode)
+// Claim asks the current leader to start job on its assigned worker.
+// It fails with a conflict (see IsConflict) if the job changed hands meanwhile.
+// Claims are idempotent, so retrying one whose response was lost is safe.
+func (c *LeaderClient) Claim(job *store.Job) error {
+	payload := map[string]interface{}{
+		"id":          job.ID,
+		"worker_id":   job.WorkerID,
+		"retry_count": job.RetryCount,
 	}
-	return nil
+	return c.Post("/claim", payload)
 }
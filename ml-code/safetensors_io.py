@@ -0,0 +1,59 @@
+"""
+Minimal safetensors reader/writer, so the Go leader can merge models without torch.
+
+Layout: an 8-byte little-endian header length, a JSON header mapping each tensor
+name to {"dtype", "shape", "data_offsets"} (plus optional "__metadata__" string
+pairs), then the raw little-endian tensor data. Only F32 and F64 are used.
+"""
+
+import json
+import os
+import struct
+
+import numpy as np
+
+_DTYPES = {"F32": np.dtype("<f4"), "F64": np.dtype("<f8")}
+
+
+def save_file(tensors, path, metadata=None):
+    """Write a dict of name -> numpy array (or torch tensor) to path, atomically."""
+    header = {}
+    if metadata:
+        header["__metadata__"] = {k: str(v) for k, v in metadata.items()}
+    chunks = []
+    offset = 0
+    for name in sorted(tensors):
+        array = tensors[name]
+        if hasattr(array, "detach"):  # torch.Tensor
+            array = array.detach().cpu().float().numpy()
+        dtype = "F64" if array.dtype == np.float64 else "F32"
+        data = np.ascontiguousarray(array, dtype=_DTYPES[dtype]).tobytes()
+        header[name] = {"dtype": dtype, "shape": list(array.shape), "data_offsets": [offset, offset + len(data)]}
+        chunks.append(data)
+        offset += len(data)
+
+    header_bytes = json.dumps(header).encode("utf-8")
+    header_bytes += b" " * (-len(header_bytes) % 8)
+    # Write under a temporary name and rename, so the leader never reads a half-written model
+    tmp_path = f"{path}.tmp{os.getpid()}"
+    with open(tmp_path, "wb") as f:
+        f.write(struct.pack("<Q", len(header_bytes)))
+        f.write(header_bytes)
+        for data in chunks:
+            f.write(data)
+    os.replace(tmp_path, path)
+
+
+def load_file(path):
+    """Read path into (dict of name -> numpy array, metadata dict)."""
+    with open(path, "rb") as f:
+        (header_size,) = struct.unpack("<Q", f.read(8))
+        header = json.loads(f.read(header_size))
+        body = f.read()
+    metadata = header.pop("__metadata__", {})
+    tensors = {}
+    for name, entry in header.items():
+        begin, end = entry["data_offsets"]
+        array = np.frombuffer(body[begin:end], dtype=_DTYPES[entry["dtype"]])
+        tensors[name] = array.reshape(entry["shape"])
+    return tensors, metadata
@@ -9,26 +9,33 @@ import torch.optim as optim
 from torch.utils.data import DataLoader, Subset
 from torchvision import datasets, transforms
 
+from safetensors_io import load_file, save_file
+
 # --- 1. ARGUMENT PARSING (Contract with Go) ---
 parser = argparse.ArgumentParser(description='Distributed MNIST Training')
 parser.add_argument('job_id', type=str, help='Job ID')
-parser.add_argument('--shard_index', type=str, default='node-1', help='Worker shard index (e.g., node-1, node-2)')
+parser.add_argument('--shard_index', type=str, default='0', help='Zero-based shard index (legacy: node-1, node-2)')
 parser.add_argument('--total_shards', type=int, default=1, help='Total number of shards (cluster size)')
+parser.add_argument('--init_model', type=str, default='', help='Global model (safetensors) to start from, in multi-round training')
+parser.add_argument('--output_dir', type=str, default='./raft-data', help="Where to write this job's model (the node's data_dir)")
 
 args = parser.parse_args()
 JOB_ID = args.job_id
 SHARD_INDEX = args.shard_index
 TOTAL_SHARDS = args.total_shards
 
-# Convert node-1, node-2, node-3 to numeric index 0, 1, 2
-if SHARD_INDEX.startswith('node-'):
+# Go passes a zero-based index; older callers passed node-1, node-2, node-3
+if SHARD_INDEX.isdigit():
+    NUMERIC_SHARD = int(SHARD_INDEX)
+elif SHARD_INDEX.startswith('node-'):
     NUMERIC_SHARD = int(SHARD_INDEX.split('-')[1]) - 1
 else:
     NUMERIC_SHARD = 0
 
-# Define where to save this specific job's model
-os.makedirs("./raft-data", exist_ok=True)
-MODEL_PATH = f"./raft-data/{JOB_ID}_model.pth"
+# Define where to save this specific job's model.
+# safetensors, so the leader can average the weights in Go without torch.
+os.makedirs(args.output_dir, exist_ok=True)
+MODEL_PATH = os.path.join(args.output_dir, f"{JOB_ID}_model.safetensors")
 
 print(f"[Python] 🚀 Starting Training for Job: {JOB_ID}")
 print(f"[Python] 📊 Shard {NUMERIC_SHARD + 1}/{TOTAL_SHARDS} (Worker: {SHARD_INDEX})")
@@ -123,12 +130,18 @@ def main():
         train_loader = DataLoader(train_dataset, batch_size=64, shuffle=True)
 
         model = SimpleNN().to(device)
+        if args.init_model:
+            # Later rounds of multi-round training continue from the last global model
+            tensors, _ = load_file(args.init_model)
+            model.load_state_dict({name: torch.from_numpy(array.copy()) for name, array in tensors.items()})
+            print(f"[Python] 🔁 Starting from global model {args.init_model}")
+            sys.stdout.flush()
         
         # Train
         loss, acc = train_model(model, train_loader, device, epochs=1)
         
-        # Save Model
-        torch.save(model.state_dict(), MODEL_PATH)
+        # Save Model. num_samples weights this shard in the federated average.
+        save_file(model.state_dict(), MODEL_PATH, metadata={"job_id": JOB_ID, "num_samples": end - start})
         
         # --- 2. JSON OUTPUT (Contract with Go) ---
         # This MUST be the last thing printed
@@ -137,16 +150,19 @@ def main():
             "status": "COMPLETED",
             "accuracy": acc,
             "loss": loss,
-            "model_path": MODEL_PATH
+            "model_path": MODEL_PATH,
+            "num_samples": end - start
         }
         print(json.dumps(result))
 
     except Exception as e:
         # If Python crashes, print a JSON error so Go knows it failed
+        # error_type lets the worker tell e.g. MemoryError from a bug in the script
         error_result = {
             "job_id": JOB_ID,
             "status": "FAILED",
-            "error": str(e)
+            "error": str(e),
+            "error_type": type(e).__name__
         }
         print(json.dumps(error_result))
         sys.exit(1)
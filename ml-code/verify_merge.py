@@ -1,19 +1,31 @@
 #!/usr/bin/env python3
 """
 Verify that the merged model weights are mathematically correct.
-Loads the global model and individual shard models to check averaging.
+Loads the global model and individual shard models to check averaging:
+the global model is the average of the shards weighted by their num_samples.
 """
 
-import torch
 import sys
 import os
 
+import numpy as np
+
+from safetensors_io import load_file
+
 def load_model(path):
-    """Load a PyTorch model state dict."""
+    """Load a safetensors model as (tensors, metadata)."""
     if not os.path.exists(path):
         print(f"❌ Model not found: {path}")
         return None
-    return torch.load(path, map_location='cpu')
+    return load_file(path)
+
+def sample_weight(metadata):
+    """The shard's weight in the average, as the Go aggregator computes it."""
+    try:
+        n = float(metadata.get("num_samples", "1"))
+    except ValueError:
+        return 1.0
+    return n if n > 0 else 1.0
 
 def verify_averaging(global_path, shard_paths):
     """
@@ -21,17 +33,20 @@ def verify_averaging(global_path, shard_paths):
     """
     print("🔍 Loading models...")
     
-    global_model = load_model(global_path)
-    if global_model is None:
+    loaded = load_model(global_path)
+    if loaded is None:
         return False
+    global_model, _ = loaded
     
     shard_models = []
+    shard_weights = []
     for path in shard_paths:
         model = load_model(path)
         if model is None:
             print(f"⚠️  Skipping missing shard: {path}")
         else:
-            shard_models.append(model)
+            shard_models.append(model[0])
+            shard_weights.append(sample_weight(model[1]))
     
     if len(shard_models) == 0:
         print("❌ No shard models found!")
@@ -49,13 +64,13 @@ def verify_averaging(global_path, shard_paths):
     
     global_weights = global_model[first_layer_key]
     print(f"   Shape: {global_weights.shape}")
-    print(f"   Mean: {global_weights.mean().item():.6f}")
-    print(f"   Std: {global_weights.std().item():.6f}")
-    print(f"   Min: {global_weights.min().item():.6f}")
-    print(f"   Max: {global_weights.max().item():.6f}")
+    print(f"   Mean: {global_weights.mean():.6f}")
+    print(f"   Std: {global_weights.std():.6f}")
+    print(f"   Min: {global_weights.min():.6f}")
+    print(f"   Max: {global_weights.max():.6f}")
     
     # Check if weights are all zeros (bad!)
-    if torch.allclose(global_weights, torch.zeros_like(global_weights)):
+    if np.allclose(global_weights, 0):
         print("❌ Global model weights are all zeros!")
         return False
     
@@ -73,19 +88,21 @@ def verify_averaging(global_path, shard_paths):
             
         global_param = global_model[param_name]
         
-        # Compute expected average
+        # Compute expected (sample-weighted) average
         shard_params = []
-        for shard in shard_models:
+        weights = []
+        for shard, weight in zip(shard_models, shard_weights):
             if param_name in shard:
-                shard_params.append(shard[param_name])
+                shard_params.append(shard[param_name].astype(np.float64))
+                weights.append(weight)
         
         if len(shard_params) == 0:
             continue
         
-        expected_avg = torch.stack(shard_params).mean(dim=0)
+        expected_avg = np.average(np.stack(shard_params), axis=0, weights=weights)
         
         # Compare with global model
-        diff = torch.abs(global_param - expected_avg).max().item()
+        diff = np.abs(global_param - expected_avg).max()
         
         if diff > 1e-5:  # Tolerance for floating point errors
             errors.append(f"   {param_name}: max diff = {diff:.8f}")
@@ -110,17 +127,17 @@ def verify_averaging(global_path, shard_paths):
     for i, shard in enumerate(shard_models[:3]):
         if first_layer_key in shard:
             weights = shard[first_layer_key]
-            print(f"   Shard {i+1}: {weights.flatten()[:5].tolist()}")
+            print(f"   Shard {i+1} ({shard_weights[i]:.0f} samples): {weights.flatten()[:5].tolist()}")
     
     return True
 
 def main():
     # Default paths
-    global_path = "./raft-data/test-federated_global.pth"
+    global_path = "./raft-data/test-federated_global.safetensors"
     shard_paths = [
-        "./raft-data/test-federated-node-1_model.pth",
-        "./raft-data/test-federated-node-2_model.pth",
-        "./raft-data/test-federated-node-3_model.pth",
+        "./raft-data/test-federated-node-1_model.safetensors",
+        "./raft-data/test-federated-node-2_model.safetensors",
+        "./raft-data/test-federated-node-3_model.safetensors",
     ]
     
     # Allow custom paths from command line
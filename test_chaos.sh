@@ -162,7 +162,7 @@ fi
 echo -e "\n${YELLOW}Step 10: Checking for merged global model${NC}"
 sleep 10
 
-MERGED_MODEL="./raft-data/resilience-test_global.pth"
+MERGED_MODEL="./raft-data/resilience-test_global.safetensors"
 if [ -f "$MERGED_MODEL" ]; then
     echo -e "${GREEN}✓ Global model created successfully!${NC}"
     ls -lh "$MERGED_MODEL"
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+)
+
+func TestSafetensorsRoundTrip(t *testing.T) {
+	model := &aggregation.Model{
+		Tensors: map[string]*aggregation.Tensor{
+			"fc1.weight": {DType: aggregation.F32, Shape: []int{2, 3}, Data: []float64{1, 2, 3, 4, 5, 6}},
+			"fc1.bias":   {DType: aggregation.F64, Shape: []int{2}, Data: []float64{0.1, -0.2}},
+			"step":       {DType: aggregation.F32, Data: []float64{7}},
+		},
+		Metadata: map[string]string{"num_samples": "20000"},
+	}
+	data, err := aggregation.Encode(model)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	if headerSize := binary.LittleEndian.Uint64(data); headerSize%8 != 0 {
+		t.Errorf("expected the header padded to 8 bytes, got %d", headerSize)
+	}
+
+	decoded, err := aggregation.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if decoded.Metadata["num_samples"] != "20000" || len(decoded.Tensors) != 3 {
+		t.Fatalf("unexpected model: %+v", decoded)
+	}
+	bias := decoded.Tensors["fc1.bias"]
+	if bias.DType != aggregation.F64 || bias.Data[1] != -0.2 {
+		t.Fatalf("unexpected bias: %+v", bias)
+	}
+	weight := decoded.Tensors["fc1.weight"]
+	if weight.Shape[0] != 2 || weight.Shape[1] != 3 || weight.Data[5] != 6 {
+		t.Fatalf("unexpected weight: %+v", weight)
+	}
+	if step := decoded.Tensors["step"]; len(step.Shape) != 0 || step.Data[0] != 7 {
+		t.Fatalf("unexpected scalar: %+v", step)
+	}
+}
+
+func TestSafetensorsReadsPythonLayout(t *testing.T) {
+	// What ml-code/safetensors_io.py writes: json.dumps header padded with spaces
+	header := `{"__metadata__": {"job_id": "job-1-node-1", "num_samples": "3"}, "w": {"dtype": "F32", "shape": [2], "data_offsets": [0, 8]}}`
+	for len(header)%8 != 0 {
+		header += " "
+	}
+	data := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	data = append(data, header...)
+	data = binary.LittleEndian.AppendUint32(data, math.Float32bits(1.5))
+	data = binary.LittleEndian.AppendUint32(data, math.Float32bits(-2))
+
+	model, err := aggregation.Decode(data)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if w := model.Tensors["w"]; w.Data[0] != 1.5 || w.Data[1] != -2 || aggregation.SampleWeight(model) != 3 {
+		t.Fatalf("unexpected model: %+v %+v", w, model.Metadata)
+	}
+
+	// Truncated data must be rejected, not read out of bounds
+	if _, err := aggregation.Decode(data[:len(data)-2]); !errors.Is(err, aggregation.ErrMalformed) {
+		t.Fatalf("expected ErrMalformed for truncated data, got %v", err)
+	}
+	bf16 := []byte(`{"w": {"dtype": "BF16", "shape": [1], "data_offsets": [0, 2]}}`)
+	data = append(binary.LittleEndian.AppendUint64(nil, uint64(len(bf16))), append(bf16, 0, 0)...)
+	if _, err := aggregation.Decode(data); !errors.Is(err, aggregation.ErrUnsupportedDType) {
+		t.Fatalf("expected ErrUnsupportedDType, got %v", err)
+	}
+}
+
+func TestFedAvgWeightsBySamples(t *testing.T) {
+	shard := func(samples string, values ...float64) *aggregation.Model {
+		return &aggregation.Model{
+			Tensors:  map[string]*aggregation.Tensor{"w": {DType: aggregation.F32, Shape: []int{len(values)}, Data: values}},
+			Metadata: map[string]string{"num_samples": samples},
+		}
+	}
+
+	// Weights come from the num_samples train.py records, read back from disk
+	dir := t.TempDir()
+	var models []*aggregation.Model
+	var weights []float64
+	for i, m := range []*aggregation.Model{shard("10", 1, 10), shard("30", 5, 50)} {
+		path := filepath.Join(dir, []string{"a", "b"}[i]+".safetensors")
+		if err := aggregation.WriteFile(path, m); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		read, err := aggregation.ReadFile(path)
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		models = append(models, read)
+		weights = append(weights, aggregation.SampleWeight(read))
+	}
+
+	global, err := aggregation.FedAvg(models, weights)
+	if err != nil {
+		t.Fatalf("FedAvg failed: %v", err)
+	}
+	// (10*1 + 30*5) / 40 = 4, (10*10 + 30*50) / 40 = 40
+	if w := global.Tensors["w"].Data; w[0] != 4 || w[1] != 40 {
+		t.Fatalf("expected [4 40], got %v", w)
+	}
+
+	// Without sample counts every model counts the same
+	avg, _ := aggregation.FedAvg([]*aggregation.Model{shard("", 1, 10), shard("", 5, 50)}, []float64{1, 1})
+	if w := avg.Tensors["w"].Data; w[0] != 3 || w[1] != 30 {
+		t.Fatalf("expected [3 30], got %v", w)
+	}
+
+	// Models of different architectures cannot be averaged
+	if _, err := aggregation.FedAvg([]*aggregation.Model{shard("1", 1, 2), shard("1", 1, 2, 3)}, []float64{1, 1}); err == nil {
+		t.Fatalf("expected a shape mismatch error")
+	}
+	if _, err := aggregation.ReadFile(filepath.Join(dir, "missing.safetensors")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/config"
+)
+
+func TestConfigDefaultsAreValid(t *testing.T) {
+	cfg := config.Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("defaults should validate: %v", err)
+	}
+	if cfg.JobTimeout.Duration != 15*time.Second || cfg.MaxRetries != 2 {
+		t.Fatalf("unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"job_timeout": "60s", "max_retries": 5, "data_dir": "/var/lib/distraft", "raft": {"commit_timeout": "20ms"}}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg := config.Default()
+	if err := cfg.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	// Environment beats the file
+	env := map[string]string{"DISTRAFT_MAX_RETRIES": "7"}
+	if err := cfg.ApplyEnv(func(k string) (string, bool) { v, ok := env[k]; return v, ok }); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+
+	// Flags beat the environment; unset flags leave values alone
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := config.BindFlags(fs)
+	if err := fs.Parse([]string{"-max-retries", "9"}); err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	if err := flags.Apply(&cfg); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	if cfg.MaxRetries != 9 {
+		t.Fatalf("expected flag to win for max_retries, got %d", cfg.MaxRetries)
+	}
+	if cfg.JobTimeout.Duration != 60*time.Second || cfg.DataDir != "/var/lib/distraft" {
+		t.Fatalf("expected file values to survive, got %+v", cfg)
+	}
+	if cfg.Raft.CommitTimeout.Duration != 20*time.Millisecond || cfg.Raft.ElectionTimeout.Duration != time.Second {
+		t.Fatalf("expected nested raft values merged with defaults, got %+v", cfg.Raft)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config: %v", err)
+	}
+}
+
+func TestConfigRejectsBadSettings(t *testing.T) {
+	cfg := config.Default()
+	cfg.JobTimeout.Duration = 4 * time.Second // less than two heartbeats
+	cfg.MaxRetries = -1
+	cfg.Raft.LeaderLeaseTimeout.Duration = 2 * time.Second
+	cfg.RetryBackoffCap.Duration = time.Second // below the 5s base
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("expected validation errors")
+	}
+	for _, want := range []string{"job_timeout", "max_retries", "leader_lease_timeout", "retry_backoff_cap"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error mentioning %s, got: %v", want, err)
+		}
+	}
+
+	// Heartbeats are tracked in whole seconds, so 1.5s would silently become 1s
+	subSecond := config.Default()
+	subSecond.HeartbeatInterval.Duration = 500 * time.Millisecond
+	subSecond.JobTimeout.Duration = 1500 * time.Millisecond
+	if err := subSecond.Validate(); err == nil || !strings.Contains(err.Error(), "whole number of seconds") {
+		t.Errorf("expected sub-second job_timeout to be rejected, got: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "typo.json")
+	os.WriteFile(path, []byte(`{"job_timout": "60s"}`), 0o600)
+	typo := config.Default()
+	if err := typo.LoadFile(path); err == nil {
+		t.Fatalf("expected unknown key to be rejected")
+	}
+}
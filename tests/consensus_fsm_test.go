@@ -3,6 +3,7 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"testing"
 
@@ -89,6 +90,657 @@ func TestFSMApplySubmitParentJob(t *testing.T) {
 	}
 }
 
+func TestFSMApplySubmitParentJobUsesRecordedWorkers(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+
+	// A 5-node cluster with non-sequential IDs gets one shard per recorded worker
+	workers := []string{"alpha", "beta", "gamma", "delta", "epsilon"}
+	event := consensus.LogEvent{
+		Type:    consensus.CmdSubmitParentJob,
+		JobID:   "job-5",
+		Data:    &store.Job{ID: "job-5", Type: "mnist_train"},
+		Workers: workers,
+	}
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+
+	for i, workerID := range workers {
+		subJobID := consensus.SubJobID("job-5", workerID)
+		job, ok := state.GetJob(subJobID)
+		if !ok {
+			t.Fatalf("sub-job %s not found in state", subJobID)
+		}
+		if job.WorkerID != workerID || job.ParentID != "job-5" {
+			t.Fatalf("sub-job %s has wrong assignment: %+v", subJobID, job)
+		}
+		if job.ShardIndex != i || job.TotalShards != len(workers) {
+			t.Fatalf("sub-job %s has wrong shard %d/%d", subJobID, job.ShardIndex, job.TotalShards)
+		}
+	}
+}
+
+func TestFSMSubmitRecordsParentJob(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+	workers := []string{"alpha", "beta", "gamma", "delta"}
+	submit := consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: "job-1", Workers: workers, Timestamp: 100,
+		Job: &store.Job{ID: "job-1", Type: "mnist_train", RetryPolicy: &store.RetryPolicy{MaxAttempts: 4},
+			Aggregator: &store.AggregatorConfig{Name: "krum", Byzantine: 1}}}
+	if got := fsm.Apply(&raft.Log{Index: 7, Term: 1, Data: consensus.MustMarshalEvent(submit)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+
+	parent, ok := state.GetParent("job-1")
+	if !ok {
+		t.Fatalf("parent record not found")
+	}
+	// Children stay in shard (worker) order, not ID order
+	for i, workerID := range workers {
+		if parent.ChildIDs[i] != consensus.SubJobID("job-1", workerID) {
+			t.Fatalf("unexpected child order: %v", parent.ChildIDs)
+		}
+	}
+	if parent.Type != "mnist_train" || parent.SubmittedAt != 100 || parent.CreateIndex != 7 || parent.RetryPolicy.MaxAttempts != 4 ||
+		parent.Aggregator.Name != "krum" {
+		t.Fatalf("unexpected parent record: %+v", parent)
+	}
+	if parent.Status != store.ParentPending || parent.Progress != (store.ParentProgress{Total: 4, Pending: 4}) {
+		t.Fatalf("expected 4 pending children, got %s %+v", parent.Status, parent.Progress)
+	}
+
+	set := func(workerID string, status store.JobStatus) {
+		job, _ := state.GetJob(consensus.SubJobID("job-1", workerID))
+		job.Status = status
+		job.ResultURL = job.ID + ".pth"
+		state.Apply(job.ID, job)
+	}
+	expect := func(status store.ParentStatus) {
+		t.Helper()
+		if parent, _ := state.GetParent("job-1"); parent.Status != status {
+			t.Fatalf("expected parent %s, got %s %+v", status, parent.Status, parent.Progress)
+		}
+	}
+	set("alpha", store.StatusRunning)
+	expect(store.ParentRunning)
+	set("beta", store.StatusFailed)
+	expect(store.ParentPartiallyFailed)
+	set("beta", store.StatusCompleted)
+	set("alpha", store.StatusCompleted)
+	set("gamma", store.StatusCompleted)
+	expect(store.ParentRunning)
+	set("delta", store.StatusCompleted)
+	expect(store.ParentCompleted)
+
+	record := consensus.LogEvent{Type: consensus.CmdAggregate, JobID: "job-1", Timestamp: 200,
+		Aggregation: &store.Aggregation{ParentID: "job-1", Status: store.AggregationMerged, ModelPath: "raft-data/job-1_global.pth", NumModels: 4}}
+	if got := fsm.Apply(&raft.Log{Index: 20, Term: 1, Data: consensus.MustMarshalEvent(record)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+	parent, _ = state.GetParent("job-1")
+	if parent.Status != store.ParentMerged || parent.GlobalModelPath != "raft-data/job-1_global.pth" || parent.NumModels != 4 ||
+		parent.Progress.Completed != 4 || parent.ModifyIndex != 20 {
+		t.Fatalf("unexpected merged parent: %+v", parent)
+	}
+}
+
+func TestFSMRejectsResubmittedIDs(t *testing.T) {
+	state := store.NewState()
+	state.Apply("solo", &store.Job{ID: "solo", Status: store.StatusCompleted})
+	fsm := consensus.NewFSM(state)
+	submit := func(index uint64, id string, training *store.TrainingConfig) interface{} {
+		event := consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: id, Workers: []string{"node-1", "node-2"},
+			Job: &store.Job{ID: id, Training: training}}
+		return fsm.Apply(&raft.Log{Index: index, Term: 1, Data: consensus.MustMarshalEvent(event)})
+	}
+	if got := submit(1, "job-1", nil); got != nil {
+		t.Fatalf("expected first submission to succeed, got %v", got)
+	}
+	if got := submit(2, "run", &store.TrainingConfig{Rounds: 2}); got != nil {
+		t.Fatalf("expected training submission to succeed, got %v", got)
+	}
+
+	for _, tc := range []struct {
+		id       string
+		training *store.TrainingConfig
+	}{
+		{"job-1", nil}, // The parent job
+		{"job-1", &store.TrainingConfig{Rounds: 1}}, // ... also as a training run
+		{"run", nil},          // The training run
+		{"run-round-1", nil},  // Its round's parent job
+		{"solo", nil},         // A plain job
+		{"job-1-node-1", nil}, // A sub-job
+	} {
+		if err, _ := submit(3, tc.id, tc.training).(error); !errors.Is(err, consensus.ErrJobExists) {
+			t.Errorf("resubmitting %s: expected ErrJobExists, got %v", tc.id, err)
+		}
+	}
+	if parent, _ := state.GetParent("job-1"); parent.CreateIndex != 1 || parent.ModifyIndex != 1 {
+		t.Fatalf("rejected submission changed the parent: %+v", parent)
+	}
+}
+
+func TestFSMApplyCancelParentJob(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+
+	submit := consensus.LogEvent{
+		Type:    consensus.CmdSubmitParentJob,
+		JobID:   "job-1",
+		Data:    &store.Job{ID: "job-1", Type: "mnist_train"},
+		Workers: []string{"node-1", "node-2", "node-3"},
+	}
+	fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(submit)})
+
+	// node-1 already finished its shard; cancelling must not rewrite history
+	done, _ := state.GetJob("job-1-node-1")
+	completed := *done
+	completed.Status = store.StatusCompleted
+	state.Apply(completed.ID, &completed)
+
+	cancel := consensus.LogEvent{Type: consensus.CmdCancelJob, JobID: "job-1", Timestamp: 1234}
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(cancel)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+
+	expected := map[string]store.JobStatus{
+		"job-1-node-1": store.StatusCompleted,
+		"job-1-node-2": store.StatusCancelled,
+		"job-1-node-3": store.StatusCancelled,
+	}
+	for id, status := range expected {
+		job, _ := state.GetJob(id)
+		if job.Status != status {
+			t.Fatalf("%s: expected %s, got %s", id, status, job.Status)
+		}
+	}
+	if job, _ := state.GetJob("job-1-node-2"); job.UpdatedAt != 1234 {
+		t.Fatalf("expected UpdatedAt from the log entry, got %d", job.UpdatedAt)
+	}
+
+	missing := consensus.LogEvent{Type: consensus.CmdCancelJob, JobID: "no-such-job"}
+	got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(missing)})
+	if err, ok := got.(error); !ok || !errors.Is(err, consensus.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", got)
+	}
+}
+
+func TestFSMApplyHeartbeat(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-2", StartedAt: 100})
+	fsm := consensus.NewFSM(state)
+
+	hb := consensus.LogEvent{Type: consensus.CmdHeartbeat, JobID: "job-1", WorkerID: "node-2", Timestamp: 150}
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(hb)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+	if job, _ := state.GetJob("job-1"); job.LastHeartbeat != 150 {
+		t.Fatalf("expected LastHeartbeat=150, got %d", job.LastHeartbeat)
+	}
+
+	// A worker the job is not assigned to (e.g. its previous owner) cannot keep it alive
+	stale := consensus.LogEvent{Type: consensus.CmdHeartbeat, JobID: "job-1", WorkerID: "node-3", Timestamp: 200}
+	got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(stale)})
+	if err, ok := got.(error); !ok || !errors.Is(err, consensus.ErrNotAssigned) {
+		t.Fatalf("expected ErrNotAssigned, got %v", got)
+	}
+	if job, _ := state.GetJob("job-1"); job.LastHeartbeat != 150 {
+		t.Fatalf("rejected heartbeat changed LastHeartbeat to %d", job.LastHeartbeat)
+	}
+}
+
+func TestFSMApplyClaimJob(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusPending, WorkerID: "node-3", RetryCount: 1,
+		Failure: store.Failure{Error: "no heartbeat from node-2 for 20s", FailureCategory: store.FailureTimeout}})
+	fsm := consensus.NewFSM(state)
+
+	claim := func(workerID string, epoch int) interface{} {
+		event := consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1", WorkerID: workerID, RetryEpoch: epoch, Timestamp: 500}
+		return fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)})
+	}
+
+	// The previous owner saw epoch 0 and the wrong worker: both preconditions fail
+	if err, ok := claim("node-2", 0).(error); !ok || !errors.Is(err, consensus.ErrClaimConflict) {
+		t.Fatalf("expected conflict for previous owner, got %v", err)
+	}
+	// Right worker but stale epoch
+	if err, ok := claim("node-3", 0).(error); !ok || !errors.Is(err, consensus.ErrClaimConflict) {
+		t.Fatalf("expected conflict for stale epoch, got %v", err)
+	}
+
+	if got := claim("node-3", 1); got != nil {
+		t.Fatalf("expected claim to win, got %v", got)
+	}
+	job, _ := state.GetJob("job-1")
+	if job.Status != store.StatusRunning || job.StartedAt != 500 {
+		t.Fatalf("claimed job not running: %+v", job)
+	}
+	if job.Error != "" || job.FailureCategory != "" {
+		t.Fatalf("expected claim to clear the previous failure, got %+v", job)
+	}
+
+	// A duplicate claim (e.g. a request retried after a lost response) succeeds
+	// but does not start the job again
+	if got := claim("node-3", 1); got != nil {
+		t.Fatalf("expected duplicate claim to succeed, got %v", got)
+	}
+	if again, _ := state.GetJob("job-1"); again.Revision != job.Revision || again.StartedAt != 500 {
+		t.Fatalf("duplicate claim changed the job: %+v", again)
+	}
+	// Other workers still lose
+	if err, ok := claim("node-2", 1).(error); !ok || !errors.Is(err, consensus.ErrClaimConflict) {
+		t.Fatalf("expected conflict for another worker, got %v", err)
+	}
+}
+
+func TestFSMApplyUpdateJob(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-1"})
+	fsm := consensus.NewFSM(state)
+
+	update := func(job *store.Job, pre *consensus.Precondition) *consensus.UpdateResult {
+		event := consensus.LogEvent{Type: consensus.CmdUpdateJob, JobID: job.ID, Job: job, Precondition: pre}
+		res, ok := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)}).(*consensus.UpdateResult)
+		if !ok {
+			t.Fatalf("expected *UpdateResult from Apply")
+		}
+		return res
+	}
+
+	// The health monitor reassigns the job based on revision 1
+	reassigned := &store.Job{ID: "job-1", Status: store.StatusPending, WorkerID: "node-2", RetryCount: 1}
+	res := update(reassigned, &consensus.Precondition{Revision: 1})
+	if res.Err != nil || res.Job.WorkerID != "node-2" || res.Job.Revision != 2 {
+		t.Fatalf("expected reassignment at revision 2, got %+v (err %v)", res.Job, res.Err)
+	}
+
+	// The old worker's late COMPLETED must not clobber the reassignment
+	late := &store.Job{ID: "job-1", Status: store.StatusCompleted, WorkerID: "node-1"}
+	res = update(late, &consensus.Precondition{Status: store.StatusRunning, WorkerID: "node-1"})
+	if !errors.Is(res.Err, consensus.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed, got %v", res.Err)
+	}
+	if res.Job == nil || res.Job.WorkerID != "node-2" {
+		t.Fatalf("expected rejected result to carry the current job, got %+v", res.Job)
+	}
+
+	// A stale revision is rejected too
+	res = update(&store.Job{ID: "job-1", Status: store.StatusFailed}, &consensus.Precondition{Revision: 1})
+	if !errors.Is(res.Err, consensus.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed for stale revision, got %v", res.Err)
+	}
+	if job, _ := state.GetJob("job-1"); job.Status != store.StatusPending || job.Revision != 2 {
+		t.Fatalf("rejected updates changed the job: %+v", job)
+	}
+
+	// A cancel that commits before a report wins, precondition or not
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusCancelled, WorkerID: "node-2"})
+	res = update(&store.Job{ID: "job-1", Status: store.StatusCompleted, WorkerID: "node-2"}, nil)
+	if !errors.Is(res.Err, consensus.ErrPreconditionFailed) || res.Job.Status != store.StatusCancelled {
+		t.Fatalf("expected the cancelled job to stay cancelled, got %+v (err %v)", res.Job, res.Err)
+	}
+
+	// UPDATE_JOB never creates jobs
+	res = update(&store.Job{ID: "missing"}, nil)
+	if !errors.Is(res.Err, consensus.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", res.Err)
+	}
+}
+
+func TestFSMStampsRaftPosition(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+
+	create := consensus.LogEvent{Type: consensus.CmdSetJob, Job: &store.Job{ID: "job-1", Status: store.StatusPending, WorkerID: "node-1"}}
+	fsm.Apply(&raft.Log{Index: 5, Term: 2, Data: consensus.MustMarshalEvent(create)})
+
+	claim := consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1", WorkerID: "node-1", Timestamp: 100}
+	fsm.Apply(&raft.Log{Index: 9, Term: 3, Data: consensus.MustMarshalEvent(claim)})
+
+	job, _ := state.GetJob("job-1")
+	if job.CreateIndex != 5 || job.CreateTerm != 2 || job.ModifyIndex != 9 || job.ModifyTerm != 3 {
+		t.Fatalf("unexpected stamps: create %d/%d modify %d/%d", job.CreateIndex, job.CreateTerm, job.ModifyIndex, job.ModifyTerm)
+	}
+
+	// A writer that saw the job before the claim (index 5) loses
+	stale := consensus.LogEvent{
+		Type:         consensus.CmdUpdateJob,
+		Job:          &store.Job{ID: "job-1", Status: store.StatusFailed},
+		Precondition: &consensus.Precondition{ModifyIndex: 5},
+	}
+	res := fsm.Apply(&raft.Log{Index: 10, Term: 3, Data: consensus.MustMarshalEvent(stale)}).(*consensus.UpdateResult)
+	if !errors.Is(res.Err, consensus.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed for stale modify index, got %v", res.Err)
+	}
+}
+
+func TestFSMRecordsJobHistory(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+	var index uint64
+	apply := func(event consensus.LogEvent) interface{} {
+		index++
+		return fsm.Apply(&raft.Log{Index: index, Term: 1, Data: consensus.MustMarshalEvent(event)})
+	}
+
+	apply(consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: "job-1", Job: &store.Job{ID: "job-1"}, Workers: []string{"node-2"}, Timestamp: 100})
+	apply(consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1-node-2", WorkerID: "node-2", Timestamp: 110})
+	apply(consensus.LogEvent{Type: consensus.CmdHeartbeat, JobID: "job-1-node-2", WorkerID: "node-2", Timestamp: 115})
+
+	// The health monitor gives up on node-2
+	policy := &store.RetryPolicy{MaxAttempts: 3, BackoffBaseSeconds: 10, BackoffCapSeconds: 60}
+	apply(consensus.LogEvent{Type: consensus.CmdFailJob, JobID: "job-1-node-2", Cause: store.EventHeartbeatLost, Reason: "no heartbeat",
+		Failure: &store.Failure{Error: "no heartbeat", FailureCategory: store.FailureTimeout}, RetryPolicy: policy, NextWorker: "node-3", Timestamp: 140})
+
+	apply(consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1-node-2", WorkerID: "node-3", RetryEpoch: 1, Timestamp: 150})
+	apply(consensus.LogEvent{Type: consensus.CmdFailJob, JobID: "job-1-node-2",
+		Failure: &store.Failure{Error: "exit status 1", ExitCode: 1, FailureCategory: store.FailureUserCode}, RetryPolicy: policy, Timestamp: 160})
+
+	want := []store.JobEvent{
+		{Type: store.EventCreated, Time: 100, Index: 1, Term: 1, WorkerID: "node-2"},
+		{Type: store.EventClaimed, Time: 110, Index: 2, Term: 1, WorkerID: "node-2"},
+		{Type: store.EventHeartbeatLost, Time: 140, Index: 4, Term: 1, WorkerID: "node-2", Reason: "no heartbeat"},
+		{Type: store.EventReassigned, Time: 140, Index: 4, Term: 1, WorkerID: "node-3", FromWorker: "node-2", Reason: "no heartbeat"},
+		{Type: store.EventClaimed, Time: 150, Index: 5, Term: 1, WorkerID: "node-3"},
+		{Type: store.EventFailed, Time: 160, Index: 6, Term: 1, WorkerID: "node-3", Reason: "exit status 1"},
+	}
+	got := state.GetJobEvents("job-1-node-2")
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	// History survives a snapshot
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+	restored := store.NewState()
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if events := restored.GetJobEvents("job-1-node-2"); len(events) != len(want) || events[5] != want[5] {
+		t.Fatalf("history did not round-trip: %+v", events)
+	}
+}
+
+func TestFSMApplyFailJobFollowsRetryPolicy(t *testing.T) {
+	state := store.NewState()
+	policy := &store.RetryPolicy{MaxAttempts: 2, BackoffBaseSeconds: 30, BackoffCapSeconds: 300}
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-1", RetryPolicy: policy})
+	fsm := consensus.NewFSM(state)
+
+	fail := func(category store.FailureCategory, ts int64) *consensus.UpdateResult {
+		event := consensus.LogEvent{Type: consensus.CmdFailJob, JobID: "job-1", NextWorker: "node-2", Timestamp: ts,
+			Failure: &store.Failure{Error: "boom", FailureCategory: category}}
+		return fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)}).(*consensus.UpdateResult)
+	}
+	claim := func(ts int64) interface{} {
+		event := consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1", WorkerID: "node-2", RetryEpoch: 1, Timestamp: ts}
+		return fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)})
+	}
+
+	// First infra failure: retried on node-2 after the base backoff
+	res := fail(store.FailureInfra, 1000)
+	if res.Err != nil || res.Job.Status != store.StatusPending || res.Job.WorkerID != "node-2" || res.Job.NotBefore != 1030 || res.Job.RetryCount != 1 {
+		t.Fatalf("expected retry on node-2 not before 1030, got %+v (err %v)", res.Job, res.Err)
+	}
+	if err, ok := claim(1010).(error); !ok || !errors.Is(err, consensus.ErrClaimConflict) {
+		t.Fatalf("expected claim during backoff to conflict, got %v", err)
+	}
+	if got := claim(1030); got != nil {
+		t.Fatalf("expected claim after backoff to win, got %v", got)
+	}
+
+	// Second failure: attempts are used up
+	res = fail(store.FailureInfra, 1100)
+	if res.Err != nil || res.Job.Status != store.StatusFailed || res.Job.FailureCategory != store.FailureInfra {
+		t.Fatalf("expected FAILED after max attempts, got %+v (err %v)", res.Job, res.Err)
+	}
+
+	// A terminal job cannot fail again
+	if res = fail(store.FailureInfra, 1200); !errors.Is(res.Err, consensus.ErrPreconditionFailed) {
+		t.Fatalf("expected ErrPreconditionFailed for a FAILED job, got %v", res.Err)
+	}
+}
+
+func TestFSMApplyFailJobDoesNotRetryUserCode(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-1"})
+	fsm := consensus.NewFSM(state)
+
+	// No policy on the job: the default recorded in the entry applies
+	event := consensus.LogEvent{Type: consensus.CmdFailJob, JobID: "job-1", Timestamp: 100,
+		RetryPolicy: &store.RetryPolicy{MaxAttempts: 5, BackoffBaseSeconds: 1, BackoffCapSeconds: 1},
+		Failure:     &store.Failure{Error: "ZeroDivisionError", ExitCode: 1, FailureCategory: store.FailureUserCode}}
+	res := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)}).(*consensus.UpdateResult)
+	if res.Err != nil || res.Job.Status != store.StatusFailed || res.Job.ExitCode != 1 {
+		t.Fatalf("expected a script error to fail the job for good, got %+v (err %v)", res.Job, res.Err)
+	}
+}
+
+func TestFSMApplyRequeueJob(t *testing.T) {
+	state := store.NewState()
+	policy := &store.RetryPolicy{MaxAttempts: 2, BackoffBaseSeconds: 30, BackoffCapSeconds: 300}
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-1", RetryCount: 1, RetryPolicy: policy})
+	fsm := consensus.NewFSM(state)
+	apply := func(event consensus.LogEvent) *consensus.UpdateResult {
+		return fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)}).(*consensus.UpdateResult)
+	}
+
+	// Only FAILED jobs can leave the dead letter
+	if res := apply(consensus.LogEvent{Type: consensus.CmdRequeueJob, JobID: "job-1"}); !errors.Is(res.Err, consensus.ErrPreconditionFailed) {
+		t.Fatalf("expected requeue of a RUNNING job to fail, got %v", res.Err)
+	}
+	if res := apply(consensus.LogEvent{Type: consensus.CmdRequeueJob, JobID: "missing"}); !errors.Is(res.Err, consensus.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", res.Err)
+	}
+
+	// Second attempt fails: out of attempts
+	infra := &store.Failure{Error: "disk full", FailureCategory: store.FailureInfra}
+	if res := apply(consensus.LogEvent{Type: consensus.CmdFailJob, JobID: "job-1", Failure: infra, Timestamp: 100}); res.Job.Status != store.StatusFailed {
+		t.Fatalf("expected FAILED, got %+v", res.Job)
+	}
+
+	res := apply(consensus.LogEvent{Type: consensus.CmdRequeueJob, JobID: "job-1", NextWorker: "node-3", Reason: "disk replaced", Timestamp: 200})
+	if res.Err != nil {
+		t.Fatalf("requeue failed: %v", res.Err)
+	}
+	if job := res.Job; job.Status != store.StatusPending || job.WorkerID != "node-3" || job.RetryCount != 2 || job.Requeues != 1 || job.NotBefore != 0 {
+		t.Fatalf("unexpected requeued job: %+v", job)
+	}
+
+	// A claim for the attempt before the requeue loses; one for the new attempt wins
+	claim := consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1", WorkerID: "node-3", RetryEpoch: 1, Timestamp: 210}
+	if err, _ := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(claim)}).(error); !errors.Is(err, consensus.ErrClaimConflict) {
+		t.Fatalf("expected stale claim to conflict, got %v", err)
+	}
+	claim.RetryEpoch = 2
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(claim)}); got != nil {
+		t.Fatalf("expected claim to win, got %v", got)
+	}
+
+	// The policy counts attempts afresh: the next failure is retried again
+	res = apply(consensus.LogEvent{Type: consensus.CmdFailJob, JobID: "job-1", Failure: infra, Timestamp: 300})
+	if res.Job.Status != store.StatusPending || res.Job.NotBefore != 330 {
+		t.Fatalf("expected a retry after requeue, got %+v", res.Job)
+	}
+
+	events := state.GetJobEvents("job-1")
+	if len(events) < 2 || events[1].Type != store.EventReassigned || events[1].Reason != "disk replaced" || events[1].FromWorker != "node-1" {
+		t.Fatalf("expected the requeue in the history, got %+v", events)
+	}
+}
+
+func TestFSMRecordsAggregationOnce(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1-node-1", &store.Job{ID: "job-1-node-1", ParentID: "job-1", Status: store.StatusCompleted})
+	fsm := consensus.NewFSM(state)
+	record := func(index uint64, a *store.Aggregation) interface{} {
+		event := consensus.LogEvent{Type: consensus.CmdAggregate, JobID: a.ParentID, Aggregation: a, Timestamp: 100}
+		return fsm.Apply(&raft.Log{Index: index, Term: 2, Data: consensus.MustMarshalEvent(event)})
+	}
+
+	if got := record(10, &store.Aggregation{ParentID: "job-1", Status: store.AggregationMerged, ModelPath: "global.pth", NumModels: 1}); got != nil {
+		t.Fatalf("expected first record to apply, got %v", got)
+	}
+	// A second leader's merge of the same parent is rejected
+	err, _ := record(11, &store.Aggregation{ParentID: "job-1", Status: store.AggregationMerged, ModelPath: "other.pth"}).(error)
+	if !errors.Is(err, consensus.ErrAlreadyAggregated) {
+		t.Fatalf("expected ErrAlreadyAggregated, got %v", err)
+	}
+	if err, _ := record(12, &store.Aggregation{ParentID: "job-2"}).(error); !errors.Is(err, consensus.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound for an unknown parent, got %v", err)
+	}
+
+	want := store.Aggregation{ParentID: "job-1", Status: store.AggregationMerged, ModelPath: "global.pth", NumModels: 1, Time: 100, Index: 10, Term: 2}
+	if got, ok := state.GetAggregation("job-1"); !ok || *got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	// The record survives a snapshot, so a new leader does not merge again
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := store.NewState()
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got, ok := restored.GetAggregation("job-1"); !ok || *got != want {
+		t.Fatalf("expected %+v after restore, got %+v", want, got)
+	}
+}
+
+func TestFSMRunsTrainingRounds(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+	index := uint64(0)
+	apply := func(event consensus.LogEvent) interface{} {
+		index++
+		event.Timestamp = int64(100 + index)
+		return fsm.Apply(&raft.Log{Index: index, Term: 1, Data: consensus.MustMarshalEvent(event)})
+	}
+	advance := func(round *store.Round, workers ...string) error {
+		err, _ := apply(consensus.LogEvent{Type: consensus.CmdAdvanceRound, JobID: "run-1", Round: round, Workers: workers}).(error)
+		return err
+	}
+
+	submit := consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: "run-1", Workers: []string{"node-1", "node-2"},
+		Job: &store.Job{ID: "run-1", Type: "mnist_train", Training: &store.TrainingConfig{Rounds: 3, ConvergenceThreshold: 0.5},
+			Aggregator: &store.AggregatorConfig{Name: "median"}}}
+	if got := apply(submit); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+	run, ok := state.GetTraining("run-1")
+	if !ok || run.Status != store.TrainingRunning || len(run.Rounds) != 1 || run.Rounds[0].ParentID != "run-1-round-1" {
+		t.Fatalf("unexpected training run: %+v", run)
+	}
+	// Every round is an ordinary parent job that inherits the run's aggregator
+	parent, ok := state.GetParent("run-1-round-1")
+	if !ok || parent.TrainingID != "run-1" || parent.Round != 1 || parent.Aggregator.Name != "median" || len(parent.ChildIDs) != 2 {
+		t.Fatalf("unexpected round parent: %+v", parent)
+	}
+	if job, _ := state.GetJob("run-1-round-1-node-2"); job == nil || job.InitialModel != "" || job.ShardIndex != 1 {
+		t.Fatalf("unexpected round 1 sub-job: %+v", job)
+	}
+
+	// Round 1 merged: round 2 starts from its global model on the current members
+	merged := &store.Round{Round: 1, Status: store.ParentMerged, ModelPath: "g1.safetensors", NumModels: 2, Loss: 0.9}
+	if err := advance(merged, "node-1", "node-2", "node-3"); err != nil {
+		t.Fatalf("advancing round 1 failed: %v", err)
+	}
+	if job, _ := state.GetJob("run-1-round-2-node-3"); job == nil || job.InitialModel != "g1.safetensors" || job.TotalShards != 3 {
+		t.Fatalf("unexpected round 2 sub-job: %+v", job)
+	}
+	// A second leader measuring the same round is rejected
+	if err := advance(merged, "node-1"); !errors.Is(err, consensus.ErrRoundAdvanced) {
+		t.Fatalf("expected ErrRoundAdvanced, got %v", err)
+	}
+
+	// Round 2 barely moved the model: the run has converged
+	if err := advance(&store.Round{Round: 2, Status: store.ParentMerged, ModelPath: "g2.safetensors", UpdateNorm: 0.1}, "node-1"); err != nil {
+		t.Fatalf("advancing round 2 failed: %v", err)
+	}
+	run, _ = state.GetTraining("run-1")
+	if run.Status != store.TrainingConverged || len(run.Rounds) != 2 || run.GlobalModelPath != "g2.safetensors" || run.FinishedAt == 0 {
+		t.Fatalf("expected run-1 to converge after round 2, got %+v", run)
+	}
+	if r := run.Rounds[0]; r.Loss != 0.9 || r.ParentID != "run-1-round-1" || r.StartedAt == 0 || r.FinishedAt == 0 {
+		t.Fatalf("expected round 1 metrics to be kept, got %+v", r)
+	}
+	if r := run.Rounds[1]; r.InitialModel != "g1.safetensors" || r.Status != store.ParentMerged {
+		t.Fatalf("unexpected round 2: %+v", r)
+	}
+
+	// Runs survive a snapshot, so a new leader picks up where the old one stopped
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := store.NewState()
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if got, ok := restored.GetTraining("run-1"); !ok || got.Status != store.TrainingConverged || len(got.Rounds) != 2 {
+		t.Fatalf("expected run-1 after restore, got %+v", got)
+	}
+
+	// A failed round fails the run; cancelling a run cancels its current round
+	submit.JobID, submit.Job.ID = "run-2", "run-2"
+	apply(submit)
+	if err, _ := apply(consensus.LogEvent{Type: consensus.CmdAdvanceRound, JobID: "run-2",
+		Round: &store.Round{Round: 1, Status: store.ParentFailed, Error: "merge failed"}}).(error); err != nil {
+		t.Fatalf("advancing run-2 failed: %v", err)
+	}
+	if run, _ := state.GetTraining("run-2"); run.Status != store.TrainingFailed || run.Rounds[0].Error != "merge failed" {
+		t.Fatalf("expected run-2 to fail, got %+v", run)
+	}
+	submit.JobID, submit.Job.ID = "run-3", "run-3"
+	apply(submit)
+	if got := apply(consensus.LogEvent{Type: consensus.CmdCancelJob, JobID: "run-3"}); got != nil {
+		t.Fatalf("expected cancel to apply, got %v", got)
+	}
+	if run, _ := state.GetTraining("run-3"); run.Status != store.TrainingCancelled || run.Rounds[0].Status != store.ParentCancelled {
+		t.Fatalf("expected run-3 cancelled, got %+v", run)
+	}
+	if job, _ := state.GetJob("run-3-round-1-node-1"); job.Status != store.StatusCancelled {
+		t.Fatalf("expected the current round's sub-jobs cancelled, got %s", job.Status)
+	}
+
+	// A job already submitted under the next round's ID is never taken over:
+	// it keeps its recorded merge, and the run fails instead
+	submit.JobID, submit.Job.ID = "run-5", "run-5"
+	apply(submit)
+	squatter := submit
+	squatter.JobID, squatter.Job = "run-5-round-2", &store.Job{ID: "run-5-round-2"}
+	apply(squatter)
+	apply(consensus.LogEvent{Type: consensus.CmdAggregate, JobID: "run-5-round-2",
+		Aggregation: &store.Aggregation{ParentID: "run-5-round-2", Status: store.AggregationMerged, ModelPath: "stale.safetensors"}})
+	if err, _ := apply(consensus.LogEvent{Type: consensus.CmdAdvanceRound, JobID: "run-5", Workers: []string{"node-1"},
+		Round: &store.Round{Round: 1, Status: store.ParentMerged, ModelPath: "g1.safetensors"}}).(error); err != nil {
+		t.Fatalf("advancing run-5 failed: %v", err)
+	}
+	if run, _ := state.GetTraining("run-5"); run.Status != store.TrainingFailed || len(run.Rounds) != 1 || run.Error == "" {
+		t.Fatalf("expected run-5 to fail on the taken round ID, got %+v", run)
+	}
+	if parent, _ := state.GetParent("run-5-round-2"); parent.TrainingID != "" || parent.GlobalModelPath != "stale.safetensors" {
+		t.Fatalf("expected the existing parent to be left alone, got %+v", parent)
+	}
+
+	// Invalid configs are rejected
+	submit.JobID, submit.Job.ID, submit.Job.Training = "run-4", "run-4", &store.TrainingConfig{}
+	if err, _ := apply(submit).(error); err == nil {
+		t.Fatalf("expected a run without rounds to be rejected")
+	}
+}
+
 func TestFSMSnapshotAndRestore(t *testing.T) {
 	state := store.NewState()
 	state.Apply("job-1", &store.Job{ID: "job-1", Type: "mnist_train", Status: store.StatusRunning, WorkerID: "worker-a"})
@@ -107,11 +759,13 @@ func TestFSMSnapshotAndRestore(t *testing.T) {
 		t.Fatalf("expected sink to be closed")
 	}
 
-	var restoredData map[string]*store.Job
+	var restoredData struct {
+		Jobs map[string]*store.Job `json:"jobs"`
+	}
 	if err := json.Unmarshal(sink.buf.Bytes(), &restoredData); err != nil {
 		t.Fatalf("failed to decode snapshot bytes: %v", err)
 	}
-	job, ok := restoredData["job-1"]
+	job, ok := restoredData.Jobs["job-1"]
 	if !ok || job.Status != store.StatusRunning {
 		t.Fatalf("snapshot missing job data: %+v", job)
 	}
@@ -129,6 +783,38 @@ func TestFSMSnapshotAndRestore(t *testing.T) {
 	}
 }
 
+func TestFSMApplySetAndRemoveNode(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+
+	event := consensus.LogEvent{
+		Type: consensus.CmdSetNode,
+		Node: &store.Node{ID: "node-2", RaftAddr: "localhost:7001", HTTPAddr: "localhost:8001"},
+	}
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(event)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+
+	node, ok := state.GetNodeByRaftAddr("localhost:7001")
+	if !ok || node.ID != "node-2" || node.HTTPAddr != "localhost:8001" {
+		t.Fatalf("node not registered correctly: %+v", node)
+	}
+
+	remove := consensus.LogEvent{Type: consensus.CmdRemoveNode, Node: &store.Node{ID: "node-2"}}
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(remove)}); got != nil {
+		t.Fatalf("expected nil apply result, got %v", got)
+	}
+	if _, ok := state.GetNode("node-2"); ok {
+		t.Fatalf("expected node-2 to be removed from the registry")
+	}
+
+	// A registration without a node ID is rejected
+	bad := consensus.LogEvent{Type: consensus.CmdSetNode, Node: &store.Node{RaftAddr: "localhost:7002"}}
+	if got := fsm.Apply(&raft.Log{Data: consensus.MustMarshalEvent(bad)}); got == nil {
+		t.Fatalf("expected error for node without ID")
+	}
+}
+
 // testSnapshotSink captures snapshot bytes for verification.
 type testSnapshotSink struct {
 	buf      bytes.Buffer
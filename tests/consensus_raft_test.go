@@ -1,11 +1,16 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/raft"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 )
@@ -35,6 +40,17 @@ func TestNewRaftNodeCreatesResources(t *testing.T) {
 	}
 }
 
+func TestRaftNodeCloseTwice(t *testing.T) {
+	node, _ := newBootstrappedNode(t, "node-1", store.NewState())
+	if err := node.Close(); err != nil {
+		t.Fatalf("first close failed: %v", err)
+	}
+	// A second close (here the cleanup's) must not panic
+	if err := node.Close(); err != nil {
+		t.Fatalf("second close failed: %v", err)
+	}
+}
+
 // createRaftNodeWithTimeout guards against hangs when constructing Raft.
 func createRaftNodeWithTimeout(t *testing.T, ctor func() (*consensus.RaftNode, error)) *consensus.RaftNode {
 	t.Helper()
@@ -58,3 +74,256 @@ func createRaftNodeWithTimeout(t *testing.T, ctor func() (*consensus.RaftNode, e
 	}
 	return node
 }
+
+func TestMembersIncludesNonvoters(t *testing.T) {
+	node, raftAddr := newBootstrappedNode(t, "node-1", store.NewState())
+
+	// The worker-only node never has to answer: a single voter is already a quorum
+	if err := node.Raft.AddNonvoter("worker-1", "127.0.0.1:1", 0, 5*time.Second).Error(); err != nil {
+		t.Fatalf("AddNonvoter failed: %v", err)
+	}
+
+	members, err := node.Members()
+	if err != nil {
+		t.Fatalf("Members failed: %v", err)
+	}
+	if len(members) != 2 || members[0] != "node-1" || members[1] != "worker-1" {
+		t.Fatalf("expected [node-1 worker-1], got %v", members)
+	}
+
+	future := node.Raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		t.Fatalf("GetConfiguration failed: %v", err)
+	}
+	for _, server := range future.Configuration().Servers {
+		if server.ID == "worker-1" && server.Suffrage != raft.Nonvoter {
+			t.Fatalf("worker-1 should not have a vote")
+		}
+		if server.ID == "node-1" && string(server.Address) != raftAddr {
+			t.Fatalf("unexpected address for node-1: %s", server.Address)
+		}
+	}
+}
+
+func TestRemoveNodeLeavesRegistryUntilRemoved(t *testing.T) {
+	state := store.NewState()
+	node, _ := newBootstrappedNode(t, "node-1", state)
+	if err := node.RegisterSelf("node-1", "127.0.0.1:1", "127.0.0.1:2"); err != nil {
+		t.Fatalf("RegisterSelf failed: %v", err)
+	}
+	if err := node.Raft.AddNonvoter("worker-1", "127.0.0.1:3", 0, 5*time.Second).Error(); err != nil {
+		t.Fatalf("AddNonvoter failed: %v", err)
+	}
+	register := consensus.LogEvent{Type: consensus.CmdSetNode,
+		Node: &store.Node{ID: "worker-1", RaftAddr: "127.0.0.1:3", HTTPAddr: "127.0.0.1:4"}}
+	if err := node.Raft.Apply(consensus.MustMarshalEvent(register), 5*time.Second).Error(); err != nil {
+		t.Fatalf("registering worker-1 failed: %v", err)
+	}
+
+	// The leader cannot remove itself, so its registry entry stays
+	if err := node.RemoveNode("node-1"); err == nil {
+		t.Fatalf("expected the leader to refuse removing itself")
+	}
+	if _, ok := state.GetNode("node-1"); !ok {
+		t.Fatalf("node-1 left the registry although it is still a member")
+	}
+
+	if err := node.RemoveNode("worker-1"); err != nil {
+		t.Fatalf("RemoveNode failed: %v", err)
+	}
+	future := node.Raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		t.Fatalf("GetConfiguration failed: %v", err)
+	}
+	if servers := future.Configuration().Servers; len(servers) != 1 || servers[0].ID != "node-1" {
+		t.Fatalf("expected only node-1 left, got %v", servers)
+	}
+	if _, ok := state.GetNode("worker-1"); ok {
+		t.Fatalf("worker-1 still in the registry")
+	}
+}
+
+func TestUnreachableReportsPeersFailingHeartbeats(t *testing.T) {
+	node, _ := newBootstrappedNode(t, "node-1", store.NewState())
+	if got := node.Unreachable(); len(got) != 0 {
+		t.Fatalf("expected no unreachable peers, got %v", got)
+	}
+
+	// Nothing listens on this address, so every heartbeat to worker-1 fails
+	if err := node.Raft.AddNonvoter("worker-1", "127.0.0.1:1", 0, 5*time.Second).Error(); err != nil {
+		t.Fatalf("AddNonvoter failed: %v", err)
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		got := node.Unreachable()
+		if _, ok := got["worker-1"]; ok && len(got) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected worker-1 to be unreachable, got %v", got)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestConsistentReadSeesCommittedWrites(t *testing.T) {
+	state := store.NewState()
+	node, _ := newBootstrappedNode(t, "node-1", state)
+
+	event := consensus.LogEvent{
+		Type: consensus.CmdSetJob,
+		Job:  &store.Job{ID: "job-1", Status: store.StatusPending, WorkerID: "node-1"},
+	}
+	if err := node.Raft.Apply(consensus.MustMarshalEvent(event), 5*time.Second).Error(); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	if err := node.ConsistentRead(5 * time.Second); err != nil {
+		t.Fatalf("ConsistentRead failed on leader: %v", err)
+	}
+	if _, ok := state.GetJob("job-1"); !ok {
+		t.Fatalf("committed job not visible after consistent read")
+	}
+}
+
+func TestConcurrentClaimsStartJobOnce(t *testing.T) {
+	state := store.NewState()
+	node, _ := newBootstrappedNode(t, "node-1", state)
+
+	setJob := consensus.LogEvent{
+		Type: consensus.CmdSetJob,
+		Job:  &store.Job{ID: "job-1", Status: store.StatusPending, WorkerID: "node-1"},
+	}
+	if err := node.ApplyEvent(setJob, 5*time.Second); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+
+	pending, _ := state.GetJob("job-1")
+
+	// Duplicate claims by the assigned worker all succeed, but only the first starts the job
+	const claimants = 8
+	results := make(chan error, claimants)
+	for i := 0; i < claimants; i++ {
+		go func() {
+			claim := consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1", WorkerID: "node-1", Timestamp: time.Now().Unix()}
+			results <- node.ApplyEvent(claim, 5*time.Second)
+		}()
+	}
+
+	for i := 0; i < claimants; i++ {
+		if err := <-results; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	job, _ := state.GetJob("job-1")
+	if job.Status != store.StatusRunning || job.Revision != pending.Revision+1 {
+		t.Fatalf("expected the job to be started exactly once, got %+v (was revision %d)", job, pending.Revision)
+	}
+
+	// Another worker never wins
+	claim := consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1", WorkerID: "node-2", Timestamp: time.Now().Unix()}
+	if err := node.ApplyEvent(claim, 5*time.Second); !errors.Is(err, consensus.ErrClaimConflict) {
+		t.Fatalf("expected conflict for another worker, got %v", err)
+	}
+}
+
+// TestStateChangesOnlyThroughCommittedEntries runs readers that scribble on the
+// jobs they get back while entries are being committed. Run with -race: the
+// final state must be exactly what the log says, whatever the readers did.
+func TestStateChangesOnlyThroughCommittedEntries(t *testing.T) {
+	state := store.NewState()
+	node, _ := newBootstrappedNode(t, "node-1", state)
+
+	const jobs = 20
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, job := range state.GetAllJobs() {
+					job.Status = store.StatusFailed
+					job.WorkerID = "reader"
+				}
+				listed, _, _ := state.ListJobs(store.JobQuery{Status: store.StatusPending})
+				for _, job := range listed {
+					job.RetryCount++
+				}
+				if job, ok := state.GetJob("job-0"); ok {
+					job.Status = store.StatusCancelled
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < jobs; i++ {
+		event := consensus.LogEvent{
+			Type: consensus.CmdSetJob,
+			Job:  &store.Job{ID: fmt.Sprintf("job-%d", i), Status: store.StatusPending, WorkerID: "node-1"},
+		}
+		if err := node.ApplyEvent(event, 5*time.Second); err != nil {
+			t.Fatalf("apply failed: %v", err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	all := state.GetAllJobs()
+	if len(all) != jobs {
+		t.Fatalf("expected %d jobs, got %d", jobs, len(all))
+	}
+	for id, job := range all {
+		if job.Status != store.StatusPending || job.WorkerID != "node-1" || job.RetryCount != 0 {
+			t.Fatalf("job %s changed outside the log: %+v", id, job)
+		}
+	}
+	pending, _, err := state.ListJobs(store.JobQuery{Status: store.StatusPending, Limit: store.MaxPageSize})
+	if err != nil {
+		t.Fatalf("ListJobs: %v", err)
+	}
+	if len(pending) != jobs {
+		t.Fatalf("expected index to list %d PENDING jobs, got %d", jobs, len(pending))
+	}
+}
+
+// newBootstrappedNode starts a single-node cluster and waits for it to become leader.
+func newBootstrappedNode(t *testing.T, nodeID string, state *store.State) (*consensus.RaftNode, string) {
+	t.Helper()
+
+	// Reserve a free port: the bootstrap configuration needs the real address
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to reserve port: %v", err)
+	}
+	raftAddr := lis.Addr().String()
+	lis.Close()
+
+	node := createRaftNodeWithTimeout(t, func() (*consensus.RaftNode, error) {
+		return consensus.NewRaftNode(nodeID, raftAddr, t.TempDir(), state)
+	})
+	t.Cleanup(func() {
+		if err := node.Close(); err != nil {
+			t.Logf("error closing node: %v", err)
+		}
+	})
+
+	cfg := raft.Configuration{Servers: []raft.Server{{ID: raft.ServerID(nodeID), Address: raft.ServerAddress(raftAddr)}}}
+	if err := node.Raft.BootstrapCluster(cfg).Error(); err != nil {
+		t.Fatalf("bootstrap failed: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !node.IsLeader() {
+		if time.Now().After(deadline) {
+			t.Fatalf("node did not become leader")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return node, raftAddr
+}
@@ -127,7 +127,7 @@ sleep 10
 # Step 7: Verify merged model exists
 echo -e "\n${YELLOW}Step 7: Verifying merged model${NC}"
 
-MERGED_MODEL="./raft-data/test-federated_global.pth"
+MERGED_MODEL="./raft-data/test-federated_global.safetensors"
 if [ -f "$MERGED_MODEL" ]; then
     echo -e "${GREEN}✓ Merged model found: ${MERGED_MODEL}${NC}"
     ls -lh "$MERGED_MODEL"
@@ -143,7 +143,7 @@ if [ -f "$MERGED_MODEL" ]; then
 else
     echo -e "${RED}✗ Merged model not found at: ${MERGED_MODEL}${NC}"
     echo -e "\n${YELLOW}Checking what files exist in raft-data:${NC}"
-    find ./raft-data -name "*.pth" -ls
+    find ./raft-data -name "*.safetensors" -ls
     echo -e "\n${YELLOW}Checking aggregator logs in node-1:${NC}"
     grep -i "merge\|aggregat" /tmp/node1.log | tail -20
     exit 1
@@ -153,7 +153,7 @@ fi
 echo -e "\n${YELLOW}Step 8: Verifying individual shard models${NC}"
 SHARDS_FOUND=0
 for node in node-1 node-2 node-3; do
-    SHARD_MODEL="./raft-data/test-federated-${node}_model.pth"
+    SHARD_MODEL="./raft-data/test-federated-${node}_model.safetensors"
     if [ -f "$SHARD_MODEL" ]; then
         SIZE=$(stat -f%z "$SHARD_MODEL" 2>/dev/null || stat -c%s "$SHARD_MODEL" 2>/dev/null)
         echo -e "${GREEN}✓ Found shard: ${SHARD_MODEL} (${SIZE} bytes)${NC}"
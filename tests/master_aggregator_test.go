@@ -1,27 +1,106 @@
 package tests
 
 import (
-    "testing"
-    "github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/master"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 )
 
 func TestCollectParents(t *testing.T) {
-    jobs := map[string]*store.Job{
-        "job-a-node-1": {ID: "job-a-node-1"},
-        "job-a-node-2": {ID: "job-a-node-2"},
-        "job-a-node-3": {ID: "job-a-node-3"},
-        "job-b-node-1": {ID: "job-b-node-1"},
-    }
-
-    parents := map[string]struct{}{}
-    for id := range jobs {
-        if len(id) >= 7 && (id[len(id)-7:] == "-node-1" || id[len(id)-7:] == "-node-2" || id[len(id)-7:] == "-node-3") {
-            p := id[:len(id)-7]
-            parents[p] = struct{}{}
-        }
-    }
-
-    if len(parents) != 2 {
-        t.Fatalf("expected 2 parents, got %d", len(parents))
-    }
-}
\ No newline at end of file
+	jobs := map[string]*store.Job{
+		"job-a-node-1": {ID: "job-a-node-1", ParentID: "job-a", ShardIndex: 0, TotalShards: 3},
+		"job-a-node-2": {ID: "job-a-node-2", ParentID: "job-a", ShardIndex: 1, TotalShards: 3},
+		"job-a-node-3": {ID: "job-a-node-3", ParentID: "job-a", ShardIndex: 2, TotalShards: 3},
+		"job-b-node-1": {ID: "job-b-node-1", ParentID: "job-b", ShardIndex: 0, TotalShards: 5},
+		"standalone":   {ID: "standalone"},
+	}
+
+	parents := master.CollectParents(jobs, "")
+	if len(parents) != 2 {
+		t.Fatalf("expected 2 parents, got %d", len(parents))
+	}
+	for i, job := range parents["job-a"] {
+		if job.ShardIndex != i {
+			t.Fatalf("expected sub-jobs ordered by shard, got %s at position %d", job.ID, i)
+		}
+	}
+
+	if got := master.CollectParents(jobs, "job-b"); len(got) != 1 {
+		t.Fatalf("expected prefix filter to keep 1 parent, got %d", len(got))
+	}
+}
+
+func TestReadyParentsSkipsUnfinishedAndRecordedParents(t *testing.T) {
+	state := store.NewState()
+	for i, status := range []store.JobStatus{store.StatusCompleted, store.StatusCompleted} {
+		id := fmt.Sprintf("done-%d", i)
+		state.Apply(id, &store.Job{ID: id, ParentID: "done", Status: status, ResultURL: id + ".pth", ShardIndex: i, TotalShards: 2})
+	}
+	state.Apply("busy-0", &store.Job{ID: "busy-0", ParentID: "busy", Status: store.StatusCompleted, ResultURL: "busy-0.pth", TotalShards: 2})
+	state.Apply("busy-1", &store.Job{ID: "busy-1", ParentID: "busy", Status: store.StatusRunning, ShardIndex: 1, TotalShards: 2})
+	state.Apply("merged-0", &store.Job{ID: "merged-0", ParentID: "merged", Status: store.StatusCompleted, ResultURL: "m.pth", TotalShards: 1})
+	state.SetAggregation(&store.Aggregation{ParentID: "merged", Status: store.AggregationMerged, NumModels: 1})
+
+	ready := master.ReadyParents(state, "")
+	if len(ready) != 1 || len(ready["done"]) != 2 || ready["done"][0] != "done-0.pth" || ready["done"][1] != "done-1.pth" {
+		t.Fatalf("expected only done with its models in shard order, got %v", ready)
+	}
+}
+
+func TestMergeModelsRetriesFileErrorsOnly(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.safetensors")
+	m := &aggregation.Model{Tensors: map[string]*aggregation.Tensor{"w": {DType: aggregation.F32, Shape: []int{2}, Data: []float64{1, 2}}}}
+	if err := aggregation.WriteFile(good, m); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	missing := filepath.Join(dir, "missing.safetensors")
+
+	// A model that is not there (yet) is not a verdict: nothing to record
+	if result, err := master.MergeModels(dir, "p", nil, []string{good, missing}); err == nil || result != nil {
+		t.Fatalf("expected a retryable error, got %+v (%v)", result, err)
+	}
+
+	// A malformed model may still be being written...
+	broken := filepath.Join(dir, "broken.safetensors")
+	os.WriteFile(broken, []byte("not a model"), 0o644)
+	if result, err := master.MergeModels(dir, "p", nil, []string{good, broken}); err == nil || result != nil {
+		t.Fatalf("expected a retryable error for a fresh malformed model, got %+v (%v)", result, err)
+	}
+
+	// ...but once it has not changed for a while, it can never be merged
+	stale := time.Now().Add(-2 * master.ModelWriteGrace)
+	os.Chtimes(broken, stale, stale)
+	result, err := master.MergeModels(dir, "p", nil, []string{good, broken})
+	if err != nil || result.Status != store.AggregationFailed || result.Error == "" {
+		t.Fatalf("expected a FAILED aggregation, got %+v (%v)", result, err)
+	}
+
+	// Once the shard shows up, the retry merges
+	if err := aggregation.WriteFile(missing, m); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	result, err = master.MergeModels(dir, "p", nil, []string{good, missing})
+	if err != nil || result.Status != store.AggregationMerged || result.NumModels != 2 {
+		t.Fatalf("expected a merged aggregation, got %+v (%v)", result, err)
+	}
+	if result.ModelPath != filepath.Join(dir, "p_global.safetensors") {
+		t.Fatalf("expected the global model in the data dir, got %s", result.ModelPath)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp*")); len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+	global, err := aggregation.ReadFile(result.ModelPath)
+	if err != nil {
+		t.Fatalf("global model not written: %v", err)
+	}
+	if global.Metadata["num_models"] != "2" || global.Metadata["aggregator"] != "fedavg" {
+		t.Fatalf("unexpected metadata: %v", global.Metadata)
+	}
+}
@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/master"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+func TestAggregatorsResistPoisonedShard(t *testing.T) {
+	shard := func(samples string, values ...float64) *aggregation.Model {
+		return &aggregation.Model{
+			Tensors:  map[string]*aggregation.Tensor{"w": {DType: aggregation.F32, Shape: []int{len(values)}, Data: values}},
+			Metadata: map[string]string{"num_samples": samples},
+		}
+	}
+	// Four honest shards around [1, 2] and one poisoned shard claiming many samples
+	models := []*aggregation.Model{
+		shard("100", 1.0, 2.0),
+		shard("100", 1.1, 1.9),
+		shard("100", 0.9, 2.0),
+		shard("100", 1.0, 2.2),
+		shard("10000", 1000, -1000),
+	}
+
+	tests := []struct {
+		cfg  *store.AggregatorConfig
+		want []float64
+	}{
+		{nil, nil}, // FedAvg is dragged towards the poisoned shard
+		{&store.AggregatorConfig{Name: "median"}, []float64{1.0, 2.0}},
+		{&store.AggregatorConfig{Name: "trimmed_mean"}, []float64{3.1 / 3, 5.9 / 3}},            // Drops one value at each end
+		{&store.AggregatorConfig{Name: "trimmed_mean", TrimFraction: 0.4}, []float64{1.0, 2.0}}, // Down to the median
+		{&store.AggregatorConfig{Name: "krum", Byzantine: 1}, []float64{1.0, 2.0}},
+	}
+	for _, tt := range tests {
+		agg, err := master.NewAggregator(tt.cfg)
+		if err != nil {
+			t.Fatalf("NewAggregator(%+v) failed: %v", tt.cfg, err)
+		}
+		global, err := agg.Aggregate(models)
+		if err != nil {
+			t.Fatalf("%s failed: %v", agg.Name(), err)
+		}
+		w := global.Tensors["w"].Data
+		if tt.want == nil {
+			if agg.Name() != master.AggregatorFedAvg || w[0] < 900 {
+				t.Fatalf("expected FedAvg to follow the poisoned shard, got %s %v", agg.Name(), w)
+			}
+			continue
+		}
+		for i := range w {
+			if math.Abs(w[i]-tt.want[i]) > 1e-9 {
+				t.Fatalf("%s: expected %v, got %v", agg.Name(), tt.want, w)
+			}
+		}
+	}
+
+	// Krum returns one of the honest models themselves, not a mix
+	chosen, err := aggregation.Krum(models, 1)
+	if err != nil || chosen == 4 {
+		t.Fatalf("expected an honest model, got %d (%v)", chosen, err)
+	}
+	// With three shards Krum tolerates no faulty one by default, but still
+	// picks a model next to another rather than the outlier
+	if global, err := (master.Krum{}).Aggregate(models[2:]); err != nil || global.Tensors["w"].Data[0] > 2 {
+		t.Fatalf("expected an honest model from three shards, got %v (%v)", global, err)
+	}
+	if _, err := aggregation.Krum(models[:4], 1); err == nil {
+		t.Fatalf("expected Krum to need 2f+3 models")
+	}
+	// Trimming must leave something to average
+	if _, err := (master.TrimmedMean{Fraction: 0.4}).Aggregate(models[3:]); err == nil {
+		t.Fatalf("expected trimming both of two models to fail")
+	}
+}
+
+func TestNewAggregatorRejectsBadConfigs(t *testing.T) {
+	if _, err := master.NewAggregator(&store.AggregatorConfig{Name: "mean"}); !errors.Is(err, master.ErrUnknownAggregator) {
+		t.Fatalf("expected ErrUnknownAggregator, got %v", err)
+	}
+	for _, cfg := range []*store.AggregatorConfig{
+		{Name: "trimmed_mean", TrimFraction: 0.5},
+		{Name: "trimmed_mean", TrimFraction: -0.1},
+		{Name: "krum", Byzantine: -1},
+	} {
+		if _, err := master.NewAggregator(cfg); err == nil {
+			t.Errorf("expected %+v to be rejected", cfg)
+		}
+	}
+	if agg, err := master.NewAggregator(&store.AggregatorConfig{}); err != nil || agg.Name() != master.AggregatorFedAvg {
+		t.Fatalf("expected FedAvg by default, got %v (%v)", agg, err)
+	}
+}
+
+func TestValidateAggregatorChecksWorkerCount(t *testing.T) {
+	for _, tc := range []struct {
+		cfg     *store.AggregatorConfig
+		workers int
+		ok      bool
+	}{
+		{nil, 1, true},
+		{&store.AggregatorConfig{Name: "median"}, 2, true},
+		{&store.AggregatorConfig{Name: "trimmed_mean"}, 3, true},                     // Default 0.2 drops one at each end
+		{&store.AggregatorConfig{Name: "trimmed_mean", TrimFraction: 0.4}, 2, false}, // Would drop both
+		{&store.AggregatorConfig{Name: "trimmed_mean", TrimFraction: 0.4}, 5, true},
+		{&store.AggregatorConfig{Name: "krum"}, 2, false}, // Needs 3 even with no faulty model
+		{&store.AggregatorConfig{Name: "krum"}, 3, true},
+		{&store.AggregatorConfig{Name: "krum", Byzantine: 1}, 4, false},
+		{&store.AggregatorConfig{Name: "krum", Byzantine: 1}, 5, true},
+		{&store.AggregatorConfig{Name: "mean"}, 5, false},
+	} {
+		if err := master.ValidateAggregator(tc.cfg, tc.workers); (err == nil) != tc.ok {
+			t.Errorf("%+v with %d workers: expected ok=%v, got %v", tc.cfg, tc.workers, tc.ok, err)
+		}
+	}
+}
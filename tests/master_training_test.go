@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/aggregation"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/master"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+func TestFinishedRoundMeasuresMergedRound(t *testing.T) {
+	dir := t.TempDir()
+	writeModel := func(name string, values ...float64) string {
+		path := filepath.Join(dir, name)
+		m := &aggregation.Model{Tensors: map[string]*aggregation.Tensor{"w": {DType: aggregation.F32, Shape: []int{len(values)}, Data: values}}}
+		if err := aggregation.WriteFile(path, m); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+	index := uint64(0)
+	apply := func(event consensus.LogEvent) {
+		t.Helper()
+		index++
+		event.Timestamp = 100
+		if got := fsm.Apply(&raft.Log{Index: index, Term: 1, Data: consensus.MustMarshalEvent(event)}); got != nil {
+			t.Fatalf("%s failed: %v", event.Type, got)
+		}
+	}
+	apply(consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: "run", Workers: []string{"node-1", "node-2"},
+		Job: &store.Job{ID: "run", Training: &store.TrainingConfig{Rounds: 5}}})
+	apply(consensus.LogEvent{Type: consensus.CmdAdvanceRound, JobID: "run", Workers: []string{"node-1", "node-2"},
+		Round: &store.Round{Round: 1, Status: store.ParentMerged, ModelPath: writeModel("g1.safetensors", 0, 0)}})
+
+	run, _ := state.GetTraining("run")
+	if round, err := master.FinishedRound(state, run); round != nil || err != nil {
+		t.Fatalf("expected round 2 to be running, got %+v (%v)", round, err)
+	}
+
+	// Shards report their metrics on completion; the larger one counts more
+	for id, samples := range map[string]int{"run-round-2-node-1": 100, "run-round-2-node-2": 300} {
+		job, _ := state.GetJob(id)
+		job.Status, job.ResultURL = store.StatusCompleted, id+".safetensors"
+		job.NumSamples, job.Loss, job.Accuracy = samples, float64(samples)/100, 90
+		apply(consensus.LogEvent{Type: consensus.CmdSetJob, JobID: id, Job: job})
+	}
+	run, _ = state.GetTraining("run")
+	if round, err := master.FinishedRound(state, run); round != nil || err != nil {
+		t.Fatalf("expected round 2 to wait for its merge, got %+v (%v)", round, err)
+	}
+	apply(consensus.LogEvent{Type: consensus.CmdAggregate, JobID: "run-round-2", Aggregation: &store.Aggregation{
+		ParentID: "run-round-2", Status: store.AggregationMerged, ModelPath: writeModel("g2.safetensors", 3, 4), NumModels: 2}})
+
+	round, err := master.FinishedRound(state, run)
+	if err != nil || round == nil {
+		t.Fatalf("expected a finished round, got %+v (%v)", round, err)
+	}
+	// (100*1 + 300*3) / 400 = 2.5; |(3,4) - (0,0)| = 5
+	if round.Round != 2 || round.Status != store.ParentMerged || round.NumSamples != 400 || round.NumModels != 2 ||
+		math.Abs(round.Loss-2.5) > 1e-9 || round.Accuracy != 90 || math.Abs(round.UpdateNorm-5) > 1e-6 {
+		t.Fatalf("unexpected round metrics: %+v", round)
+	}
+
+	// A round with a sub-job that failed for good ends once nothing else runs
+	apply(consensus.LogEvent{Type: consensus.CmdAdvanceRound, JobID: "run", Workers: []string{"node-1", "node-2"}, Round: round})
+	run, _ = state.GetTraining("run")
+	failed, _ := state.GetJob("run-round-3-node-1")
+	failed.Status = store.StatusFailed
+	apply(consensus.LogEvent{Type: consensus.CmdSetJob, JobID: failed.ID, Job: failed})
+	if round, _ := master.FinishedRound(state, run); round != nil {
+		t.Fatalf("expected round 3 to wait for node-2, got %+v", round)
+	}
+	cancelled, _ := state.GetJob("run-round-3-node-2")
+	cancelled.Status = store.StatusCancelled
+	apply(consensus.LogEvent{Type: consensus.CmdSetJob, JobID: cancelled.ID, Job: cancelled})
+	if round, _ := master.FinishedRound(state, run); round == nil || round.Status != store.ParentFailed || round.Error == "" {
+		t.Fatalf("expected round 3 to fail, got %+v", round)
+	}
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+func TestListJobsFiltersAndSorts(t *testing.T) {
+	state := store.NewState()
+	state.Apply("a", &store.Job{ID: "a", Type: "mnist_train", Status: store.StatusPending, WorkerID: "node-1", ParentID: "p1", UpdatedAt: 10})
+	state.Apply("b", &store.Job{ID: "b", Type: "mnist_train", Status: store.StatusRunning, WorkerID: "node-2", ParentID: "p1", UpdatedAt: 30})
+	state.Apply("c", &store.Job{ID: "c", Type: "mnist_eval", Status: store.StatusPending, WorkerID: "node-1", ParentID: "p2", UpdatedAt: 20})
+
+	jobs, next, err := state.ListJobs(store.JobQuery{Status: store.StatusPending})
+	if err != nil {
+		t.Fatalf("ListJobs error: %v", err)
+	}
+	if next != "" || len(jobs) != 2 || jobs[0].ID != "c" || jobs[1].ID != "a" {
+		t.Fatalf("expected [c a] newest first, got %v", jobIDs(jobs))
+	}
+
+	jobs, _, _ = state.ListJobs(store.JobQuery{WorkerID: "node-1", Type: "mnist_train"})
+	if len(jobs) != 1 || jobs[0].ID != "a" {
+		t.Fatalf("expected [a] for node-1/mnist_train, got %v", jobIDs(jobs))
+	}
+
+	jobs, _, _ = state.ListJobs(store.JobQuery{ParentID: "p1", Ascending: true})
+	if len(jobs) != 2 || jobs[0].ID != "a" || jobs[1].ID != "b" {
+		t.Fatalf("expected [a b] oldest first, got %v", jobIDs(jobs))
+	}
+
+	// Re-applying a job moves it between index buckets
+	state.Apply("a", &store.Job{ID: "a", Type: "mnist_train", Status: store.StatusCompleted, WorkerID: "node-1", ParentID: "p1", UpdatedAt: 40})
+	jobs, _, _ = state.ListJobs(store.JobQuery{Status: store.StatusPending})
+	if len(jobs) != 1 || jobs[0].ID != "c" {
+		t.Fatalf("expected [c] after a completed, got %v", jobIDs(jobs))
+	}
+	jobs, _, _ = state.ListJobs(store.JobQuery{Status: store.StatusCompleted})
+	if len(jobs) != 1 || jobs[0].ID != "a" {
+		t.Fatalf("expected [a] in completed bucket, got %v", jobIDs(jobs))
+	}
+}
+
+func TestListJobsCursorPagination(t *testing.T) {
+	state := store.NewState()
+	for i := 0; i < 25; i++ {
+		id := fmt.Sprintf("job-%02d", i)
+		// Several jobs share an UpdatedAt so the ID tie-break is exercised
+		state.Apply(id, &store.Job{ID: id, Status: store.StatusCompleted, UpdatedAt: int64(i / 3)})
+	}
+
+	seen := map[string]bool{}
+	var order []string
+	cursor := ""
+	pages := 0
+	for {
+		jobs, next, err := state.ListJobs(store.JobQuery{Limit: 10, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListJobs error: %v", err)
+		}
+		pages++
+		for _, job := range jobs {
+			if seen[job.ID] {
+				t.Fatalf("job %s returned twice", job.ID)
+			}
+			seen[job.ID] = true
+			order = append(order, job.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	if pages != 3 || len(seen) != 25 {
+		t.Fatalf("expected 25 jobs over 3 pages, got %d jobs over %d pages", len(seen), pages)
+	}
+	// Newest first; jobs with equal UpdatedAt are ordered by ID
+	if order[0] != "job-24" || order[22] != "job-00" || order[24] != "job-02" {
+		t.Fatalf("unexpected order: %v", order)
+	}
+
+	if _, _, err := state.ListJobs(store.JobQuery{Cursor: "not a cursor"}); err == nil {
+		t.Fatalf("expected error for malformed cursor")
+	}
+}
+
+func TestListJobsPagesFilteredSelectionsInBothOrders(t *testing.T) {
+	state := store.NewState()
+	var want []string
+	for i := 0; i < 300; i++ {
+		id := fmt.Sprintf("job-%03d", i)
+		job := &store.Job{ID: id, Status: store.StatusPending, WorkerID: fmt.Sprintf("node-%d", i%3), UpdatedAt: int64(i / 4)}
+		if i%2 == 1 {
+			job.Status = store.StatusRunning
+		}
+		state.Apply(id, job)
+		if job.Status == store.StatusPending && job.WorkerID == "node-1" {
+			want = append(want, id)
+		}
+	}
+	// Moving a job re-sorts it: job-004 leaves the selection, job-006 moves to the end
+	state.Apply("job-004", &store.Job{ID: "job-004", Status: store.StatusRunning, WorkerID: "node-1", UpdatedAt: 1})
+	state.Apply("job-006", &store.Job{ID: "job-006", Status: store.StatusPending, WorkerID: "node-1", UpdatedAt: 1000})
+	want = append(slices.DeleteFunc(want, func(id string) bool { return id == "job-004" || id == "job-006" }), "job-006")
+
+	collect := func(ascending bool) []string {
+		var got []string
+		q := store.JobQuery{Status: store.StatusPending, WorkerID: "node-1", Ascending: ascending, Limit: 7}
+		for {
+			jobs, next, err := state.ListJobs(q)
+			if err != nil {
+				t.Fatalf("ListJobs error: %v", err)
+			}
+			got = append(got, jobIDs(jobs)...)
+			if next == "" {
+				return got
+			}
+			q.Cursor = next
+		}
+	}
+	if got := collect(true); !slices.Equal(got, want) {
+		t.Fatalf("ascending: expected %v, got %v", want, got)
+	}
+
+	// Newest first, equal UpdatedAt still in ID order
+	var desc []string
+	for i := len(want) - 1; i >= 0; {
+		j := i
+		u := func(id string) int64 { job, _ := state.GetJob(id); return job.UpdatedAt }
+		for j > 0 && u(want[j-1]) == u(want[i]) {
+			j--
+		}
+		desc = append(desc, want[j:i+1]...)
+		i = j - 1
+	}
+	if got := collect(false); !slices.Equal(got, desc) {
+		t.Fatalf("descending: expected %v, got %v", desc, got)
+	}
+}
+
+func TestListJobsIndexRebuiltAfterRestore(t *testing.T) {
+	state := store.NewState()
+	state.Apply("a", &store.Job{ID: "a", Status: store.StatusRunning, WorkerID: "node-3"})
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	restored := store.NewState()
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	jobs, _, _ := restored.ListJobs(store.JobQuery{WorkerID: "node-3"})
+	if len(jobs) != 1 || jobs[0].ID != "a" {
+		t.Fatalf("expected index to be rebuilt from snapshot, got %v", jobIDs(jobs))
+	}
+}
+
+func jobIDs(jobs []*store.Job) []string {
+	ids := make([]string, len(jobs))
+	for i, job := range jobs {
+		ids[i] = job.ID
+	}
+	return ids
+}
+
+func TestDeadLettersListsFailedJobsWithHistory(t *testing.T) {
+	state := store.NewState()
+	state.Apply("a", &store.Job{ID: "a", Status: store.StatusFailed, WorkerID: "node-1", ParentID: "p1", UpdatedAt: 10})
+	state.Apply("b", &store.Job{ID: "b", Status: store.StatusPending, WorkerID: "node-1", ParentID: "p1", UpdatedAt: 20, RetryCount: 1})
+	state.Apply("c", &store.Job{ID: "c", Status: store.StatusFailed, WorkerID: "node-2", ParentID: "p2", UpdatedAt: 30})
+	state.AppendJobEvents("a",
+		store.JobEvent{Type: store.EventCreated, Time: 1},
+		store.JobEvent{Type: store.EventFailed, Time: 10, Reason: "exit status 1"})
+
+	// Status in the query is ignored: only FAILED jobs are dead letters
+	letters, next, err := state.DeadLetters(store.JobQuery{ParentID: "p1", Status: store.StatusPending})
+	if err != nil {
+		t.Fatalf("DeadLetters error: %v", err)
+	}
+	if next != "" || len(letters) != 1 || letters[0].Job.ID != "a" {
+		t.Fatalf("expected only job a, got %+v", letters)
+	}
+	if len(letters[0].Events) != 2 || letters[0].Events[1].Reason != "exit status 1" {
+		t.Fatalf("expected a's history, got %+v", letters[0].Events)
+	}
+
+	letters, _, _ = state.DeadLetters(store.JobQuery{})
+	if len(letters) != 2 || letters[0].Job.ID != "c" || letters[1].Job.ID != "a" {
+		t.Fatalf("expected c then a, got %+v", letters)
+	}
+}
+
+func TestWorkerLoadCountsUnfinishedJobs(t *testing.T) {
+	state := store.NewState()
+	state.Apply("a", &store.Job{ID: "a", Status: store.StatusPending, WorkerID: "node-1"})
+	state.Apply("b", &store.Job{ID: "b", Status: store.StatusRunning, WorkerID: "node-1"})
+	state.Apply("c", &store.Job{ID: "c", Status: store.StatusCompleted, WorkerID: "node-2"})
+	state.Apply("d", &store.Job{ID: "d", Status: store.StatusRunning, WorkerID: "node-2"})
+	state.Apply("b", &store.Job{ID: "b", Status: store.StatusFailed, WorkerID: "node-1"})
+
+	load := state.WorkerLoad()
+	if len(load) != 2 || load["node-1"] != 1 || load["node-2"] != 1 {
+		t.Fatalf("expected one unfinished job on each node, got %v", load)
+	}
+}
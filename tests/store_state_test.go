@@ -2,6 +2,7 @@ package tests
 
 import (
 	"testing"
+	"time"
 
 	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
 )
@@ -16,8 +17,63 @@ func TestStateApplyAndGet(t *testing.T) {
 	if !ok {
 		t.Fatalf("expected job to exist")
 	}
-	if got != job {
-		t.Fatalf("expected stored job pointer to match original")
+	want := *job
+	want.Revision = 1
+	if got == job || *got != want {
+		t.Fatalf("expected a copy equal to the stored job, got %+v", got)
+	}
+
+	// Neither the caller's job nor returned copies may alias the stored value
+	job.Status = store.StatusFailed
+	got.WorkerID = "worker-b"
+	again, _ := state.GetJob(job.ID)
+	if again.Status != store.StatusPending || again.WorkerID != "worker-a" {
+		t.Fatalf("stored job changed outside Apply: %+v", again)
+	}
+}
+
+func TestStateApplyBumpsRevision(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusPending})
+	// Whatever revision the writer passes, the stored one only moves forward
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusRunning, Revision: 42})
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusCompleted})
+
+	job, _ := state.GetJob("job-1")
+	if job.Revision != 3 {
+		t.Fatalf("expected revision 3 after three writes, got %d", job.Revision)
+	}
+}
+
+func TestStateReadsReturnCopies(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1-node-1", &store.Job{ID: "job-1-node-1", Status: store.StatusRunning, WorkerID: "node-1", ParentID: "job-1", StartedAt: 1})
+
+	for _, job := range state.GetAllJobs() {
+		job.Status = store.StatusFailed
+	}
+	for _, job := range state.GetSubJobs("job-1") {
+		job.Status = store.StatusFailed
+	}
+	jobs, _, err := state.ListJobs(store.JobQuery{})
+	if err != nil {
+		t.Fatalf("ListJobs: %v", err)
+	}
+	for _, job := range jobs {
+		job.Status = store.StatusFailed
+	}
+	for _, job := range state.GetStuckJobs(0) {
+		job.Status = store.StatusFailed
+	}
+
+	got, _ := state.GetJob("job-1-node-1")
+	if got.Status != store.StatusRunning {
+		t.Fatalf("expected job to stay RUNNING, got %s", got.Status)
+	}
+	// The index must still agree with the stored job
+	running, _, _ := state.ListJobs(store.JobQuery{Status: store.StatusRunning})
+	if len(running) != 1 {
+		t.Fatalf("expected 1 RUNNING job in the index, got %d", len(running))
 	}
 }
 
@@ -46,3 +102,153 @@ func TestStateMarshalUnmarshal(t *testing.T) {
 		t.Fatalf("job-2 did not round-trip correctly: %+v", job)
 	}
 }
+
+func TestStateNodeRegistryRoundTrip(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1", &store.Job{ID: "job-1", Status: store.StatusPending})
+	state.SetNode(&store.Node{ID: "node-1", RaftAddr: "localhost:7000", HTTPAddr: "localhost:8000"})
+
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("marshal error: %v", err)
+	}
+
+	restored := store.NewState()
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	node, ok := restored.GetNode("node-1")
+	if !ok || node.HTTPAddr != "localhost:8000" {
+		t.Fatalf("node did not round-trip correctly: %+v", node)
+	}
+	if _, ok := restored.GetJob("job-1"); !ok {
+		t.Fatalf("job-1 missing after round-trip")
+	}
+}
+
+func TestStateUnmarshalLegacySnapshot(t *testing.T) {
+	// Snapshots taken before the node registry existed are a bare jobs map
+	legacy := []byte(`{"job-1":{"id":"job-1","type":"mnist_train","status":"COMPLETED","worker_id":"node-1","result_url":""}}`)
+
+	restored := store.NewState()
+	if err := restored.Unmarshal(legacy); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+
+	job, ok := restored.GetJob("job-1")
+	if !ok || job.Status != store.StatusCompleted {
+		t.Fatalf("legacy job not restored: %+v", job)
+	}
+	// The node registry must be usable after a legacy restore
+	restored.SetNode(&store.Node{ID: "node-1", RaftAddr: "localhost:7000"})
+	if _, ok := restored.GetNode("node-1"); !ok {
+		t.Fatalf("expected node registry to accept nodes after legacy restore")
+	}
+}
+
+func TestStateGetOrphanedJobs(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-1-node-1", &store.Job{ID: "job-1-node-1", Status: store.StatusRunning, WorkerID: "node-1"})
+	state.Apply("job-1-node-2", &store.Job{ID: "job-1-node-2", Status: store.StatusPending, WorkerID: "node-2"})
+	state.Apply("job-1-node-3", &store.Job{ID: "job-1-node-3", Status: store.StatusRunning, WorkerID: "node-3"})
+	state.Apply("job-0-node-3", &store.Job{ID: "job-0-node-3", Status: store.StatusCompleted, WorkerID: "node-3"})
+
+	// node-3 left the cluster: only its unfinished job is orphaned
+	orphaned := state.GetOrphanedJobs([]string{"node-1", "node-2"})
+	if len(orphaned) != 1 || orphaned[0].ID != "job-1-node-3" {
+		t.Fatalf("expected only job-1-node-3 to be orphaned, got %+v", orphaned)
+	}
+}
+
+func TestStateGetStuckJobsHonorsHeartbeats(t *testing.T) {
+	state := store.NewState()
+	now := time.Now().Unix()
+
+	// Started long ago but heartbeating: a healthy long training run
+	state.Apply("long-run", &store.Job{ID: "long-run", Status: store.StatusRunning, StartedAt: now - 600, LastHeartbeat: now - 2})
+	// Started long ago and silent since: the worker is gone
+	state.Apply("silent", &store.Job{ID: "silent", Status: store.StatusRunning, StartedAt: now - 600, LastHeartbeat: now - 60})
+	// Never heartbeated but only just started
+	state.Apply("fresh", &store.Job{ID: "fresh", Status: store.StatusRunning, StartedAt: now - 1})
+
+	stuck := state.GetStuckJobs(15)
+	if len(stuck) != 1 || stuck[0].ID != "silent" {
+		t.Fatalf("expected only the silent job to be stuck, got %+v", stuck)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := &store.RetryPolicy{MaxAttempts: 5, BackoffBaseSeconds: 5, BackoffCapSeconds: 30}
+	for retry, want := range map[int]int64{1: 5, 2: 10, 3: 20, 4: 30, 10: 30} {
+		if got := policy.Backoff(retry); got != want {
+			t.Errorf("retry %d: expected %ds, got %ds", retry, want, got)
+		}
+	}
+	if policy.Retryable(store.FailureUserCode) || !policy.Retryable(store.FailureTimeout) {
+		t.Errorf("unexpected default retryable categories")
+	}
+	custom := &store.RetryPolicy{RetryOn: []store.FailureCategory{store.FailureUserCode}}
+	if !custom.Retryable(store.FailureUserCode) || custom.Retryable(store.FailureInfra) {
+		t.Errorf("RetryOn not honored")
+	}
+
+	// Without a cap the delay still doubles, up to MaxBackoffSeconds and never past it
+	uncapped := &store.RetryPolicy{MaxAttempts: 100, BackoffBaseSeconds: 5}
+	for retry, want := range map[int]int64{1: 5, 3: 20, 64: store.MaxBackoffSeconds, 100: store.MaxBackoffSeconds} {
+		if got := uncapped.Backoff(retry); got != want {
+			t.Errorf("uncapped retry %d: expected %ds, got %ds", retry, want, got)
+		}
+	}
+}
+
+func TestRetryPolicyValidate(t *testing.T) {
+	if err := (&store.RetryPolicy{MaxAttempts: 3, BackoffBaseSeconds: 5, BackoffCapSeconds: 60}).Validate(); err != nil {
+		t.Fatalf("expected valid policy: %v", err)
+	}
+	for name, policy := range map[string]*store.RetryPolicy{
+		"no attempts":      {MaxAttempts: 0},
+		"negative backoff": {MaxAttempts: 3, BackoffBaseSeconds: -1},
+		"cap below base":   {MaxAttempts: 3, BackoffBaseSeconds: 60, BackoffCapSeconds: 5},
+		"cap too large":    {MaxAttempts: 3, BackoffCapSeconds: store.MaxBackoffSeconds + 1},
+		"unknown category": {MaxAttempts: 3, RetryOn: []store.FailureCategory{"segfault"}},
+	} {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestStateDerivesParentsFromOldSnapshots(t *testing.T) {
+	// Written before parent records existed
+	data := []byte(`{"version":1,"jobs":{
+		"p-node-1":{"id":"p-node-1","status":"COMPLETED","parent_id":"p","shard_index":0,"total_shards":2},
+		"p-node-2":{"id":"p-node-2","status":"CANCELLED","parent_id":"p","shard_index":1,"total_shards":2}},
+		"nodes":{}}`)
+	state := store.NewState()
+	if err := state.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	parent, ok := state.GetParent("p")
+	if !ok {
+		t.Fatalf("expected a parent record for p")
+	}
+	if len(parent.ChildIDs) != 2 || parent.ChildIDs[0] != "p-node-1" || parent.Status != store.ParentCancelled ||
+		parent.Progress != (store.ParentProgress{Total: 2, Completed: 1, Cancelled: 1}) {
+		t.Fatalf("unexpected parent: %+v", parent)
+	}
+
+	// Records survive a snapshot round trip, including what only the submission knew
+	state.CreateParentAt(store.LogPosition{Index: 9}, &store.ParentJob{ID: "q", SubmittedAt: 100, RetryPolicy: &store.RetryPolicy{MaxAttempts: 3}})
+	data, err := state.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	restored := store.NewState()
+	if err := restored.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if q, ok := restored.GetParent("q"); !ok || q.SubmittedAt != 100 || q.RetryPolicy.MaxAttempts != 3 || q.CreateIndex != 9 {
+		t.Fatalf("expected q to survive the snapshot, got %+v", q)
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/consensus"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+)
+
+// nextChange waits for the watcher's next job change
+func nextChange(t *testing.T, w *store.Watcher) store.Change {
+	t.Helper()
+	select {
+	case change, ok := <-w.C:
+		if !ok {
+			t.Fatalf("watch ended: %v", w.Err())
+		}
+		return change
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for a job change")
+		return store.Change{}
+	}
+}
+
+func TestWatchReceivesTransitionsFromFSM(t *testing.T) {
+	state := store.NewState()
+	fsm := consensus.NewFSM(state)
+	apply := func(index uint64, event consensus.LogEvent) {
+		fsm.Apply(&raft.Log{Index: index, Term: 1, Data: consensus.MustMarshalEvent(event)})
+	}
+
+	watcher, err := state.Watch(store.WatchFilter{ParentID: "job-1"}, store.ChangeID{}, 16)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer watcher.Close()
+
+	apply(1, consensus.LogEvent{Type: consensus.CmdSetJob, Job: &store.Job{ID: "other", Status: store.StatusPending}})
+	apply(2, consensus.LogEvent{Type: consensus.CmdSubmitParentJob, JobID: "job-1", Job: &store.Job{ID: "job-1"}, Workers: []string{"node-1"}})
+	apply(3, consensus.LogEvent{Type: consensus.CmdClaimJob, JobID: "job-1-node-1", WorkerID: "node-1", Timestamp: 10})
+
+	// The unrelated job is filtered out; both transitions of the sub-job arrive in order
+	if job := nextChange(t, watcher).Job; job.ID != "job-1-node-1" || job.Status != store.StatusPending || job.ModifyIndex != 2 {
+		t.Fatalf("unexpected first change: %+v", job)
+	}
+	if job := nextChange(t, watcher).Job; job.Status != store.StatusRunning || job.ModifyIndex != 3 {
+		t.Fatalf("unexpected second change: %+v", job)
+	}
+}
+
+func TestWatchReplaysFromIndex(t *testing.T) {
+	state := store.NewState()
+	for i := uint64(1); i <= 3; i++ {
+		state.ApplyAt(store.LogPosition{Index: i, Term: 1}, "job-1", &store.Job{ID: "job-1", RetryCount: int(i)})
+	}
+
+	watcher, err := state.Watch(store.WatchFilter{JobID: "job-1"}, store.ChangeID{Index: 1}, 16)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	defer watcher.Close()
+
+	for _, want := range []uint64{2, 3} {
+		if job := nextChange(t, watcher).Job; job.ModifyIndex != want {
+			t.Fatalf("expected replayed change at index %d, got %d", want, job.ModifyIndex)
+		}
+	}
+	state.ApplyAt(store.LogPosition{Index: 4, Term: 1}, "job-1", &store.Job{ID: "job-1"})
+	if job := nextChange(t, watcher).Job; job.ModifyIndex != 4 {
+		t.Fatalf("expected live change at index 4, got %d", job.ModifyIndex)
+	}
+}
+
+func TestWatchFromCompactedIndex(t *testing.T) {
+	state := store.NewState()
+	for i := 1; i <= store.WatchHistorySize+10; i++ {
+		id := fmt.Sprintf("job-%d", i)
+		state.ApplyAt(store.LogPosition{Index: uint64(i), Term: 1}, id, &store.Job{ID: id})
+	}
+
+	if _, err := state.Watch(store.WatchFilter{}, store.ChangeID{Index: 1}, 16); !errors.Is(err, store.ErrCompacted) {
+		t.Fatalf("expected ErrCompacted, got %v", err)
+	}
+	watcher, err := state.Watch(store.WatchFilter{}, store.ChangeID{Index: 20}, 16)
+	if err != nil {
+		t.Fatalf("expected a retained index to be watchable, got %v", err)
+	}
+	watcher.Close()
+}
+
+func TestWatchEndsForLaggingConsumer(t *testing.T) {
+	state := store.NewState()
+	watcher, err := state.Watch(store.WatchFilter{}, store.ChangeID{}, 2)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+
+	// Nobody reads: the writer must not block, and the watch ends instead
+	for i := 0; i < 5; i++ {
+		state.Apply("job-1", &store.Job{ID: "job-1"})
+	}
+	for range watcher.C {
+	}
+	if !errors.Is(watcher.Err(), store.ErrWatchLagged) {
+		t.Fatalf("expected ErrWatchLagged, got %v", watcher.Err())
+	}
+}
+
+func TestWatchEndsOnRestore(t *testing.T) {
+	state := store.NewState()
+	watcher, err := state.Watch(store.WatchFilter{}, store.ChangeID{}, 2)
+	if err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	if err := state.Unmarshal([]byte(`{"version":1,"jobs":{}}`)); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	for range watcher.C {
+	}
+	if !errors.Is(watcher.Err(), store.ErrStateRestored) {
+		t.Fatalf("expected ErrStateRestored, got %v", watcher.Err())
+	}
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/worker"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	cases := []struct {
+		name      string
+		exitCode  int
+		signal    syscall.Signal
+		errorType string
+		message   string
+		want      store.FailureCategory
+	}{
+		{"script exception", 1, 0, "ValueError", "bad shard index", store.FailureUserCode},
+		{"python MemoryError", 1, 0, "MemoryError", "", store.FailureOOM},
+		{"CUDA OOM", 1, 0, "RuntimeError", "CUDA out of memory. Tried to allocate 2.00 GiB", store.FailureOOM},
+		{"SIGKILL", -1, syscall.SIGKILL, "", "python script crashed: signal: killed", store.FailureOOM},
+		{"SIGKILL via shell", 137, 0, "", "python script crashed: exit status 137", store.FailureOOM},
+		{"SIGSEGV", -1, syscall.SIGSEGV, "", "python script crashed: signal: segmentation fault", store.FailureUserCode},
+		{"SIGTERM", -1, syscall.SIGTERM, "", "python script crashed: signal: terminated", store.FailureInfra},
+		{"syntax error", 1, 0, "", "python script crashed: exit status 1", store.FailureUserCode},
+	}
+	for _, tc := range cases {
+		if got := worker.ClassifyFailure(tc.exitCode, tc.signal, tc.errorType, tc.message); got != tc.want {
+			t.Errorf("%s: expected %s, got %s", tc.name, tc.want, got)
+		}
+	}
+}
+
+// TestRunPythonScriptReportsExitCode runs python on a script path that does
+// not exist (tests run from tests/, not the repo root) and checks the failure
+// keeps the exit code.
+func TestRunPythonScriptReportsExitCode(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+
+	_, err := worker.RunPythonScript(context.Background(), "job-1", "0", 1, "", t.TempDir())
+	var runErr *worker.RunError
+	if !errors.As(err, &runErr) {
+		t.Fatalf("expected *RunError, got %v", err)
+	}
+	if runErr.ExitCode != 2 || runErr.Category != store.FailureUserCode {
+		t.Fatalf("expected user_code failure with exit code 2, got %+v", runErr)
+	}
+}
+
+// TestRunPythonScriptReportsSignal runs a train.py that kills itself and
+// checks only SIGKILL counts as running out of memory.
+func TestRunPythonScriptReportsSignal(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	dir := t.TempDir()
+	t.Chdir(dir)
+	os.Mkdir("ml-code", 0o755)
+
+	for _, tc := range []struct {
+		signal syscall.Signal
+		want   store.FailureCategory
+	}{
+		{syscall.SIGKILL, store.FailureOOM},
+		{syscall.SIGABRT, store.FailureUserCode},
+		{syscall.SIGTERM, store.FailureInfra},
+	} {
+		script := fmt.Sprintf("import os\nos.kill(os.getpid(), %d)\n", int(tc.signal))
+		if err := os.WriteFile(filepath.Join("ml-code", "train.py"), []byte(script), 0o644); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		_, err := worker.RunPythonScript(context.Background(), "job-1", "0", 1, "", dir)
+		var runErr *worker.RunError
+		if !errors.As(err, &runErr) {
+			t.Fatalf("%v: expected *RunError, got %v", tc.signal, err)
+		}
+		if runErr.ExitCode != -1 || runErr.Signal != tc.signal || runErr.Category != tc.want {
+			t.Errorf("%v: expected %s failure, got %+v", tc.signal, tc.want, runErr)
+		}
+	}
+}
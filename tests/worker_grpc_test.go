@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/api"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/worker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// TestWatchJobsStreamsChanges verifies the WatchJobs RPC streams changes to
+// the requested job only.
+func TestWatchJobsStreamsChanges(t *testing.T) {
+	state := store.NewState()
+	state.ApplyAt(store.LogPosition{Index: 1, Term: 1}, "job-1", &store.Job{ID: "job-1", Status: store.StatusPending})
+	state.ApplyAt(store.LogPosition{Index: 2, Term: 1}, "job-2", &store.Job{ID: "job-2", Status: store.StatusPending})
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	api.RegisterMLWorkerServiceServer(server, worker.NewMLWorkerServer(state, 0, 0))
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := api.NewMLWorkerServiceClient(conn).WatchJobs(ctx, &api.WatchRequest{JobId: "job-1", FromIndex: 0})
+	if err != nil {
+		t.Fatalf("WatchJobs: %v", err)
+	}
+	// Headers arrive once the watch is registered on the server
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("Header: %v", err)
+	}
+
+	state.ApplyAt(store.LogPosition{Index: 3, Term: 2}, "job-2", &store.Job{ID: "job-2", Status: store.StatusRunning})
+	state.ApplyAt(store.LogPosition{Index: 4, Term: 2}, "job-1", &store.Job{ID: "job-1", Status: store.StatusRunning})
+
+	change, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %v", err)
+	}
+	if job := change.Job; change.Id != "4.0" || job.Id != "job-1" || job.Status != "RUNNING" || job.ModifyIndex != 4 || job.ModifyTerm != 2 || job.CreateIndex != 1 {
+		t.Fatalf("unexpected job change: %+v", change)
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/worker"
+)
+
+// TestLeaderClientRetriesOnLeaderChange verifies that a report sent to a dead
+// leader is retried against the newly resolved leader.
+func TestLeaderClientRetriesOnLeaderChange(t *testing.T) {
+	var updates int32
+	newLeader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/update" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		atomic.AddInt32(&updates, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer newLeader.Close()
+
+	// The old leader is already gone: its address refuses connections
+	oldLeader := httptest.NewServer(http.NotFoundHandler())
+	oldAddr := strings.TrimPrefix(oldLeader.URL, "http://")
+	oldLeader.Close()
+
+	var resolutions int32
+	resolver := worker.LeaderResolverFunc(func() (string, error) {
+		switch atomic.AddInt32(&resolutions, 1) {
+		case 1:
+			return oldAddr, nil
+		case 2:
+			return "", errors.New("election in progress")
+		default:
+			return strings.TrimPrefix(newLeader.URL, "http://"), nil
+		}
+	})
+
+	client := worker.NewLeaderClient(resolver)
+	job := &store.Job{ID: "job-1-node-1", Status: store.StatusRunning}
+	if err := client.UpdateJobStatus(job); err != nil {
+		t.Fatalf("UpdateJobStatus failed: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&updates); got != 1 {
+		t.Fatalf("expected new leader to receive 1 update, got %d", got)
+	}
+	if got := atomic.LoadInt32(&resolutions); got != 3 {
+		t.Fatalf("expected leader to be resolved 3 times, got %d", got)
+	}
+}
+
+// TestLeaderClientDoesNotRetryClientErrors verifies that a rejected report is not resent.
+func TestLeaderClientDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		http.Error(w, "Job not found", http.StatusNotFound)
+	}))
+	defer leader.Close()
+
+	client := worker.NewLeaderClient(worker.StaticLeader(strings.TrimPrefix(leader.URL, "http://")))
+	if err := client.UpdateJobStatus(&store.Job{ID: "missing"}); err == nil {
+		t.Fatalf("expected error for 404 response")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("expected exactly 1 attempt, got %d", got)
+	}
+}
+
+// TestLeaderClientCompleteJobIsConditional verifies that a completion report
+// carries its precondition and that a 409 surfaces as a conflict.
+func TestLeaderClientCompleteJobIsConditional(t *testing.T) {
+	var body map[string]interface{}
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		http.Error(w, "job update precondition failed", http.StatusConflict)
+	}))
+	defer leader.Close()
+
+	client := worker.NewLeaderClient(worker.StaticLeader(strings.TrimPrefix(leader.URL, "http://")))
+	job := &store.Job{ID: "job-1-node-1", Status: store.StatusRunning, WorkerID: "node-1"}
+	err := client.CompleteJob(job, &worker.PythonResult{JobID: job.ID, ModelPath: "model.pth"})
+	if !worker.IsConflict(err) {
+		t.Fatalf("expected conflict error, got %v", err)
+	}
+	if body["expected_status"] != "RUNNING" || body["expected_worker_id"] != "node-1" || body["status"] != "COMPLETED" {
+		t.Fatalf("unexpected report body: %v", body)
+	}
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/store"
+	"github.com/vigneshSrinivasan2005/DistRAFT/internal/worker"
+)
+
+func TestPlacementPrefersLeastLoadedHealthyWorker(t *testing.T) {
+	state := store.NewState()
+	stuck := &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-1"}
+	state.Apply(stuck.ID, stuck)
+	// node-2 is busy, node-3 is idle but unreachable, node-4 is idle
+	state.Apply("busy", &store.Job{ID: "busy", Status: store.StatusRunning, WorkerID: "node-2"})
+
+	workers := []string{"node-1", "node-2", "node-3", "node-4"}
+	unreachable := map[string]time.Time{"node-3": time.Unix(100, 0)}
+	placement := worker.NewPlacement(state, workers, unreachable)
+	if got := placement.Choose(stuck); got != "node-4" {
+		t.Fatalf("expected node-4, got %s", got)
+	}
+
+	// The choice counts as load: node-2 and node-4 now tie, and the next
+	// member after the job's worker wins the tie
+	if got := placement.Choose(&store.Job{ID: "job-2", Status: store.StatusRunning, WorkerID: "node-1"}); got != "node-2" {
+		t.Fatalf("expected node-2 on a tie, got %s (load %v)", got, placement.Load)
+	}
+}
+
+func TestPlacementAvoidsWorkersThatFailedTheJob(t *testing.T) {
+	state := store.NewState()
+	job := &store.Job{ID: "job-1", Status: store.StatusRunning, WorkerID: "node-3"}
+	state.Apply(job.ID, job)
+	state.AppendJobEvents(job.ID,
+		store.JobEvent{Type: store.EventHeartbeatLost, WorkerID: "node-1"},
+		store.JobEvent{Type: store.EventFailed, WorkerID: "node-2"})
+	state.Apply("other", &store.Job{ID: "other", Status: store.StatusPending, WorkerID: "node-4"})
+
+	workers := []string{"node-1", "node-2", "node-3", "node-4"}
+	// node-4 is the only one that has not failed job-1, even though it is busier
+	if got := worker.NewPlacement(state, workers, nil).Choose(job); got != "node-4" {
+		t.Fatalf("expected node-4, got %s", got)
+	}
+
+	// With node-4 unreachable, a reachable worker that failed before is better than none
+	unreachable := map[string]time.Time{"node-4": {}}
+	if got := worker.NewPlacement(state, workers, unreachable).Choose(job); got != "node-1" {
+		t.Fatalf("expected node-1, got %s", got)
+	}
+
+	// A single-node cluster keeps the job where it is
+	if got := worker.NewPlacement(state, []string{"node-3"}, nil).Choose(job); got != "node-3" {
+		t.Fatalf("expected node-3, got %s", got)
+	}
+}
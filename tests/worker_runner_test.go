@@ -163,14 +163,12 @@ func TestWorkerLoopWithMockLeader(t *testing.T) {
 
 	// Simulate one worker iteration (find and run pending job)
 	var jobToRun *store.Job
-	state.RLock()
-	for _, j := range state.Jobs {
+	for _, j := range state.GetAllJobs() {
 		if j.Status == store.StatusPending {
 			jobToRun = j
 			break
 		}
 	}
-	state.RUnlock()
 
 	if jobToRun == nil {
 		t.Fatalf("expected to find pending job")
@@ -234,21 +232,48 @@ func TestWorkerIntegrationWithState(t *testing.T) {
 	}
 
 	// Verify state contains all jobs
-	if len(state.Jobs) != len(jobIDs) {
-		t.Fatalf("expected %d jobs, got %d", len(jobIDs), len(state.Jobs))
+	allJobs := state.GetAllJobs()
+	if len(allJobs) != len(jobIDs) {
+		t.Fatalf("expected %d jobs, got %d", len(jobIDs), len(allJobs))
 	}
 
 	// Find pending jobs (simulating worker loop logic)
-	state.RLock()
 	pendingJobs := 0
-	for _, job := range state.Jobs {
+	for _, job := range allJobs {
 		if job.Status == store.StatusPending {
 			pendingJobs++
 		}
 	}
-	state.RUnlock()
 
 	if pendingJobs != 1 {
 		t.Errorf("expected 1 pending job, got %d", pendingJobs)
 	}
 }
+
+func TestNextRunnableJobHonorsNotBefore(t *testing.T) {
+	state := store.NewState()
+	state.Apply("job-a", &store.Job{ID: "job-a", Status: store.StatusPending, WorkerID: "node-1", NotBefore: 200})
+	state.Apply("job-b", &store.Job{ID: "job-b", Status: store.StatusPending, WorkerID: "node-1"})
+	state.Apply("job-c", &store.Job{ID: "job-c", Status: store.StatusPending, WorkerID: "node-2"})
+
+	if job := worker.NextRunnableJob(state, "node-1", 100); job == nil || job.ID != "job-b" {
+		t.Fatalf("expected job-b while job-a backs off, got %+v", job)
+	}
+	state.Apply("job-b", &store.Job{ID: "job-b", Status: store.StatusRunning, WorkerID: "node-1"})
+	if job := worker.NextRunnableJob(state, "node-1", 100); job != nil {
+		t.Fatalf("expected nothing runnable before 200, got %s", job.ID)
+	}
+	if job := worker.NextRunnableJob(state, "node-1", 200); job == nil || job.ID != "job-a" {
+		t.Fatalf("expected job-a once its backoff passed, got %+v", job)
+	}
+
+	// A runnable job behind more than a page of backing-off ones is still found
+	for i := range 40 {
+		id := fmt.Sprintf("wait-%02d", i)
+		state.Apply(id, &store.Job{ID: id, Status: store.StatusPending, WorkerID: "node-3", UpdatedAt: int64(i), NotBefore: 500})
+	}
+	state.Apply("late", &store.Job{ID: "late", Status: store.StatusPending, WorkerID: "node-3", UpdatedAt: 99})
+	if job := worker.NextRunnableJob(state, "node-3", 100); job == nil || job.ID != "late" {
+		t.Fatalf("expected late behind the backing-off jobs, got %+v", job)
+	}
+}